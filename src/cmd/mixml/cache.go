@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/redmaner/mixml/src/miuires"
+)
+
+// cache holds content hashes of files that were processed before, so unchanged files
+// can be skipped on subsequent runs. A hash covers the bytes of the file and its
+// dependencies, like the source file, and a fingerprint of the mixml version and
+// options. A nil cache is valid and never reports a file as unchanged. It is safe
+// for concurrent use.
+type cache struct {
+	sync.Mutex
+	path        string
+	command     string
+	fingerprint []byte
+	Entries     map[string]map[string]cacheEntry `json:"entries"`
+}
+
+// cacheEntry holds the hash of a file and the warnings and removals of the run that
+// stored it, so they can be repeated when the file is skipped
+type cacheEntry struct {
+	Hash     string            `json:"hash"`
+	Warnings []string          `json:"warnings,omitempty"`
+	Removed  []miuires.Removal `json:"removed,omitempty"`
+}
+
+// openCache opens the cache at path for command. The fingerprint holds everything
+// besides file contents that influences the result, like the filter configuration.
+// It returns nil if path is empty, or if the fingerprint can't be encoded, in which
+// case different options would be indistinguishable.
+func openCache(path string, command string, fingerprint ...interface{}) *cache {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(append([]interface{}{version, command}, fingerprint...))
+	if err != nil {
+		fmt.Printf("Couldn't use cache: %v\n", err)
+		return nil
+	}
+
+	c := &cache{
+		path:        path,
+		command:     command,
+		fingerprint: data,
+		Entries:     make(map[string]map[string]cacheEntry),
+	}
+
+	// A missing or corrupt cache is simply started over
+	if data, err := ioutil.ReadFile(path); err == nil {
+		if json.Unmarshal(data, c) != nil || c.Entries == nil {
+			c.Entries = make(map[string]map[string]cacheEntry)
+		}
+	}
+	if c.Entries[command] == nil {
+		c.Entries[command] = make(map[string]cacheEntry)
+	}
+	return c
+}
+
+// hash returns the hash of the fingerprint and the contents of file and deps
+func (c *cache) hash(file string, deps ...string) string {
+	h := sha256.New()
+	h.Write(c.fingerprint)
+	for _, path := range append([]string{file}, deps...) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			h.Write([]byte{0})
+			continue
+		}
+		h.Write([]byte{1})
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lookup returns the entry of file if file and deps didn't change since they were
+// stored
+func (c *cache) lookup(file string, deps ...string) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+	hash := c.hash(file, deps...)
+
+	c.Lock()
+	defer c.Unlock()
+	entry, ok := c.Entries[c.command][file]
+	return entry, ok && entry.Hash == hash
+}
+
+// store stores the current hash of file and deps, together with the warnings and
+// removals of file
+func (c *cache) store(file string, warnings []string, removed []miuires.Removal, deps ...string) {
+	if c == nil {
+		return
+	}
+	entry := cacheEntry{
+		Hash:     c.hash(file, deps...),
+		Warnings: warnings,
+		Removed:  removed,
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.Entries[c.command][file] = entry
+}
+
+// save writes the cache to disk
+func (c *cache) save() error {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// Forget files that no longer exist
+	for file := range c.Entries[c.command] {
+		if _, err := os.Stat(file); err != nil {
+			delete(c.Entries[c.command], file)
+		}
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, data, 0644)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/redmaner/mixml/src/miuires"
+)
+
+// Check function
+func check() {
+
+	if argHelp {
+		showHelpCheck()
+	}
+
+	applyProjectConfig(cmdCheck)
+	checks, err := selectChecks(argChecks)
+	if err != nil {
+		fmt.Println(err)
+		showHelpCheck()
+	}
+
+	sources := newSourceCache()
+	files := inputFiles(cmdCheck.Args())
+	issues := make([]int, len(files))
+
+	// Options that influence the result are part of the cache fingerprint
+	c := openCache(argCache, "check", checks, argSource)
+
+	runJobs(files, argJobs, func(index int, v string, out io.Writer) {
+		var deps []string
+		if argSource != "" {
+			deps = append(deps, miuires.SourcePath(v, argSource))
+		}
+		if entry, ok := c.lookup(v, deps...); ok {
+			for _, warning := range entry.Warnings {
+				fmt.Fprintf(out, "Warning: %s\n", warning)
+			}
+			if argVerbose {
+				fmt.Fprintf(out, "Unchanged %s\n", v)
+			}
+			return
+		}
+
+		res, err := miuires.NewResources(v)
+		if err != nil {
+			fmt.Fprintf(out, "An error occurred when loading %s: %v\n", v, err)
+			issues[index]++
+			return
+		}
+
+		for _, warning := range res.Warnings {
+			fmt.Fprintf(out, "Warning: %s\n", warning)
+		}
+
+		var src *miuires.Resources
+		if argSource != "" {
+			src = sources.load(res.SourcePath(argSource), out)
+		}
+
+		issues[index] += checkResources(res, src, checks, out)
+
+		// Only files without issues are cached, so issues are reported on every run
+		if issues[index] == 0 {
+			c.store(v, res.Warnings, nil, deps...)
+		}
+		if argVerbose {
+			fmt.Fprintf(out, "Checked %s\n", v)
+		}
+	})
+
+	if err := c.save(); err != nil {
+		fmt.Printf("Couldn't save cache: %v\n", err)
+	}
+
+	var total int
+	for _, n := range issues {
+		total += n
+	}
+	if total > 0 {
+		fmt.Printf("Found %d issue(s)\n", total)
+		os.Exit(1)
+	}
+}
+
+// checkResources runs checks on res, prints the issues found to out and returns the
+// number of issues. src may be nil.
+func checkResources(res, src *miuires.Resources, checks []string, out io.Writer) (issues int) {
+	for _, name := range checks {
+		for _, issue := range miuires.Checks[name](res, src) {
+			fmt.Fprintln(out, issue)
+			issues++
+		}
+	}
+	return issues
+}
+
+// selectChecks returns the names of the checks listed in the comma separated list.
+// All checks are returned when list is empty.
+func selectChecks(list string) ([]string, error) {
+	if list == "" {
+		return miuires.CheckNames, nil
+	}
+
+	var checks []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := miuires.Checks[name]; !ok {
+			return nil, fmt.Errorf("Unknown check: %s", name)
+		}
+		checks = append(checks, name)
+	}
+	return checks, nil
+}
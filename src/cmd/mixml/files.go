@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/redmaner/mixml/src/miuires"
+)
+
+// stringList is a flag that can be passed more than once
+type stringList []string
+
+// String returns the values of the flag separated by commas
+func (sl *stringList) String() string {
+	return strings.Join(*sl, ",")
+}
+
+// Set adds a value to the flag
+func (sl *stringList) Set(value string) error {
+	*sl = append(*sl, value)
+	return nil
+}
+
+// isResourceFile returns true if the file name is strings.xml, arrays.xml or plurals.xml
+func isResourceFile(name string) bool {
+	return name == miuires.FileTypeStrings || name == miuires.FileTypeArrays || name == miuires.FileTypePlurals
+}
+
+// isAppDir returns true if the directory holds the resources of an application, like
+// Settings.apk, framework-ext-res.jar or framework-res
+func isAppDir(name string) bool {
+	return filepath.Ext(name) == ".apk" || filepath.Ext(name) == ".jar" || name == "framework-res"
+}
+
+// isValuesDir returns true if the directory holds values resources, like values or
+// values-nl
+func isValuesDir(name string) bool {
+	return name == "values" || strings.HasPrefix(name, "values-")
+}
+
+// findResources returns the strings.xml, arrays.xml and plurals.xml files found in
+// the application directories below dir. If there are no application directories,
+// like in AOSP overlays, the resource files in all values directories below dir are
+// returned instead.
+func findResources(dir string) []string {
+
+	var apps []string
+	filepath.Walk(dir, func(path string, f os.FileInfo, _ error) error {
+		if f != nil && f.IsDir() && isAppDir(f.Name()) {
+			apps = append(apps, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+
+	var files []string
+	for _, v := range apps {
+		filepath.Walk(v, func(path string, f os.FileInfo, _ error) error {
+			if f != nil && !f.IsDir() && isResourceFile(f.Name()) {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	if len(apps) > 0 {
+		return files
+	}
+
+	filepath.Walk(dir, func(path string, f os.FileInfo, _ error) error {
+		if f != nil && !f.IsDir() && isResourceFile(f.Name()) && isValuesDir(filepath.Base(filepath.Dir(path))) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
+
+// selectResources returns the resource files selected by inputs. An input is a
+// directory, which is searched for application directories, a resource file, a glob
+// pattern of those, or - to read inputs from stdin, one per line. Files are only
+// returned if they match one of the include patterns, when there are any, and none
+// of the exclude patterns. Every file is returned once, in the order it was found.
+// roots holds the directory input each file was found in, files that were selected
+// directly have no root. A warning is written to out for inputs without resource files.
+func selectResources(inputs []string, stdin io.Reader, include []string, exclude []string, out io.Writer) (files []string, roots map[string]string) {
+
+	roots = make(map[string]string)
+	seen := make(map[string]bool)
+	var found int
+	add := func(path string, root string) {
+		found++
+		path = filepath.Clean(path)
+		if seen[path] || !matchesPatterns(path, include, true) || matchesPatterns(path, exclude, false) {
+			return
+		}
+		seen[path] = true
+		files = append(files, path)
+		if root != "" {
+			roots[path] = root
+		}
+	}
+
+	var expand func(input string)
+	expand = func(input string) {
+		if input == "-" {
+			scanner := bufio.NewScanner(stdin)
+			for scanner.Scan() {
+				if line := strings.TrimSpace(scanner.Text()); line != "" && line != "-" {
+					expand(line)
+				}
+			}
+			return
+		}
+
+		paths := []string{input}
+		if strings.ContainsAny(input, "*?[") {
+			paths = globFiles(input)
+		}
+
+		before := found
+		for _, path := range paths {
+			f, err := os.Stat(path)
+			switch {
+			case err != nil:
+				continue
+			case f.IsDir():
+				for _, file := range findResources(path) {
+					add(file, path)
+				}
+			case isResourceFile(f.Name()):
+				add(path, "")
+			}
+		}
+		if found == before {
+			fmt.Fprintf(out, "Warning: %s selects no resource files\n", input)
+		}
+	}
+
+	for _, input := range inputs {
+		expand(input)
+	}
+	return files, roots
+}
+
+// globFiles returns the paths matching the glob pattern. Besides the patterns of
+// filepath.Glob, ** matches any number of directories, like overlay/**/values-nl/*.
+func globFiles(pattern string) []string {
+	if !strings.Contains(pattern, "**") {
+		paths, _ := filepath.Glob(pattern)
+		return paths
+	}
+
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	re, err := globPathRegexp(pattern)
+	if err != nil {
+		return nil
+	}
+
+	// Only the directory before the first wildcard is walked
+	var root []string
+	for _, part := range strings.Split(pattern, "/") {
+		if strings.ContainsAny(part, `*?[\`) {
+			break
+		}
+		root = append(root, part)
+	}
+	dir := strings.Join(root, "/")
+	if dir == "" && strings.HasPrefix(pattern, "/") {
+		dir = "/"
+	} else if dir == "" {
+		dir = "."
+	}
+
+	var paths []string
+	filepath.Walk(filepath.FromSlash(dir), func(path string, f os.FileInfo, _ error) error {
+		if f != nil && re.MatchString(filepath.ToSlash(path)) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths
+}
+
+// globPathRegexp returns an anchored regular expression for a slash separated glob
+// pattern. * and ? don't match /, while ** matches any number of directories.
+func globPathRegexp(pattern string) (*regexp.Regexp, error) {
+
+	var buf strings.Builder
+	buf.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			buf.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			buf.WriteString(".*")
+			i++
+		case c == '*':
+			buf.WriteString("[^/]*")
+		case c == '?':
+			buf.WriteString("[^/]")
+		case c == '\\':
+			if i++; i == len(pattern) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			buf.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ]")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			} else if strings.HasPrefix(class, "^") {
+				class = `\^` + class[1:]
+			}
+			buf.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			buf.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	buf.WriteString("$")
+	return regexp.Compile(buf.String())
+}
+
+// matchesPatterns returns true if path matches one of the glob patterns. A pattern
+// matches the full path or any trailing part of it, so values-nl/*.xml matches all
+// Dutch resources, and ** matches any number of directories. If there are no patterns, empty is returned.
+func matchesPatterns(path string, patterns []string, empty bool) bool {
+	if len(patterns) == 0 {
+		return empty
+	}
+
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for _, pattern := range patterns {
+		re, err := globPathRegexp(filepath.ToSlash(pattern))
+		if err != nil {
+			continue
+		}
+		for i := range parts {
+			if re.MatchString(strings.Join(parts[i:], "/")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inputRoots holds the directory input each file returned by inputFiles was found in
+var inputRoots map[string]string
+
+// inputFiles returns the resource files selected by the --dir flags and the
+// arguments of a command, and stores the directory each file was found in. The
+// current directory is used if there are none. With --changed-since only the files
+// changed since the git ref are returned.
+func inputFiles(args []string) []string {
+	files, roots := selectInputFiles(args, os.Stdout)
+	inputRoots = roots
+	return files
+}
+
+// selectInputFiles returns the resource files selected like inputFiles, and the
+// directory input each file was found in. Warnings are written to out.
+func selectInputFiles(args []string, out io.Writer) ([]string, map[string]string) {
+	inputs := append(append([]string{}, argDirs...), args...)
+	if len(inputs) == 0 {
+		inputs = []string{"./"}
+	}
+	files, roots := selectResources(inputs, os.Stdin, argInclude, argExclude, out)
+
+	if argChangedSince != "" {
+		var err error
+		if files, err = filterChanged(files, argChangedSince); err != nil {
+			fmt.Printf("Couldn't determine changed files: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	return files, roots
+}
+
+// relativePath returns path relative to the directory input it was found in, like a
+// --dir, a directory argument or a directory read from stdin. Files that were
+// selected directly are relative to the working directory. Other paths are made
+// relative by removing the volume and leading separators.
+func relativePath(path string) string {
+	root, ok := inputRoots[path]
+	if !ok {
+		root = "."
+	}
+	if rel, ok := withinDir(root, path); ok {
+		return rel
+	}
+	path = strings.TrimPrefix(path, filepath.VolumeName(path))
+	return strings.TrimLeft(path, `/\`)
+}
+
+// withinDir returns path relative to dir, if path is inside dir
+func withinDir(dir string, path string) (string, bool) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
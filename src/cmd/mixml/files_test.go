@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTestTree creates the files below a new temporary directory and returns it
+func writeTestTree(t *testing.T, files ...string) string {
+	dir, err := ioutil.TempDir("", "mixml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("<resources>\n</resources>\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// relativeFiles returns files relative to dir with slashes
+func relativeFiles(t *testing.T, dir string, files []string) []string {
+	var rel []string
+	for _, file := range files {
+		r, err := filepath.Rel(dir, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	return rel
+}
+
+func TestMatchesPatterns(t *testing.T) {
+
+	tests := []struct {
+		path     string
+		patterns []string
+		empty    bool
+		want     bool
+	}{
+		{"tr/Settings.apk/res/values-nl/strings.xml", nil, true, true},
+		{"tr/Settings.apk/res/values-nl/strings.xml", nil, false, false},
+		{"tr/Settings.apk/res/values-nl/strings.xml", []string{"values-nl/*"}, false, true},
+		{"tr/Settings.apk/res/values-nl/strings.xml", []string{"values-de/*"}, false, false},
+		{"tr/Settings.apk/res/values-nl/strings.xml", []string{"Settings.apk/*"}, false, false},
+		{"tr/Settings.apk/res/values-nl/strings.xml", []string{"Settings.apk/**"}, false, true},
+		{"tr/Settings.apk/res/values-nl/strings.xml", []string{"tr/**/strings.xml"}, false, true},
+		{"tr/Settings.apk/res/values-nl/strings.xml", []string{"values-[a-m]*/*"}, false, false},
+		{"tr/Settings.apk/res/values-nl/strings.xml", []string{"values-[!a-m]*/*"}, false, true},
+		{"tr/Settings.apk/res/values-nl/strings.xml", []string{"values-nl/[", "*.xml"}, false, true},
+	}
+
+	for _, test := range tests {
+		if got := matchesPatterns(test.path, test.patterns, test.empty); got != test.want {
+			t.Errorf("matchesPatterns(%q, %q) = %v, want %v", test.path, test.patterns, got, test.want)
+		}
+	}
+}
+
+func TestGlobPathRegexp(t *testing.T) {
+
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"a/*/c", "a/b/c", true},
+		{"a/*/c", "a/b/x/c", false},
+		{"a/**/c", "a/c", true},
+		{"a/**/c", "a/b/x/c", true},
+		{"**/values-nl/*.xml", "x/res/values-nl/strings.xml", true},
+		{"**/values-nl/*.xml", "values-nl/strings.xml", true},
+		{"a/**", "a/b/c", true},
+		{"a?c", "a/c", false},
+		{"a.c", "abc", false},
+		{`a\*`, "a*", true},
+	}
+
+	for _, test := range tests {
+		re, err := globPathRegexp(test.pattern)
+		if err != nil {
+			t.Errorf("globPathRegexp(%q): %v", test.pattern, err)
+			continue
+		}
+		if got := re.MatchString(test.path); got != test.want {
+			t.Errorf("%q matches %q = %v, want %v", test.pattern, test.path, got, test.want)
+		}
+	}
+
+	for _, pattern := range []string{"a[", `a\`} {
+		if _, err := globPathRegexp(pattern); err == nil {
+			t.Errorf("globPathRegexp(%q): expected an error", pattern)
+		}
+	}
+}
+
+func TestSelectResources(t *testing.T) {
+
+	dir := writeTestTree(t,
+		"tr/Settings.apk/res/values-nl/strings.xml",
+		"tr/Settings.apk/res/values-nl/arrays.xml",
+		"tr/Settings.apk/res/values-nl/other.xml",
+		"tr/framework-res/res/values-de/plurals.xml",
+		"overlay/frameworks/base/core/res/res/values-nl/strings.xml",
+		"overlay/frameworks/base/core/res/res/drawable/strings.xml",
+	)
+	defer os.RemoveAll(dir)
+	path := func(p string) string { return filepath.Join(dir, filepath.FromSlash(p)) }
+
+	tests := []struct {
+		name      string
+		inputs    []string
+		stdin     string
+		include   []string
+		exclude   []string
+		want      []string
+		wantRoots map[string]string
+		warning   string
+	}{
+		{
+			name:   "directories are searched for application directories",
+			inputs: []string{path("tr")},
+			want: []string{
+				"tr/Settings.apk/res/values-nl/arrays.xml",
+				"tr/Settings.apk/res/values-nl/strings.xml",
+				"tr/framework-res/res/values-de/plurals.xml",
+			},
+			wantRoots: map[string]string{
+				"tr/Settings.apk/res/values-nl/arrays.xml":   "tr",
+				"tr/Settings.apk/res/values-nl/strings.xml":  "tr",
+				"tr/framework-res/res/values-de/plurals.xml": "tr",
+			},
+		},
+		{
+			name:      "directories without applications are searched for values directories",
+			inputs:    []string{path("overlay")},
+			want:      []string{"overlay/frameworks/base/core/res/res/values-nl/strings.xml"},
+			wantRoots: map[string]string{"overlay/frameworks/base/core/res/res/values-nl/strings.xml": "overlay"},
+		},
+		{
+			name:      "files are used as they are and selected once",
+			inputs:    []string{path("tr/Settings.apk/res/values-nl/strings.xml"), path("tr/Settings.apk/res/values-nl/strings.xml")},
+			want:      []string{"tr/Settings.apk/res/values-nl/strings.xml"},
+			wantRoots: map[string]string{},
+		},
+		{
+			name:      "patterns with ** match any number of directories",
+			inputs:    []string{path("**/values-nl/strings.xml")},
+			want:      []string{"overlay/frameworks/base/core/res/res/values-nl/strings.xml", "tr/Settings.apk/res/values-nl/strings.xml"},
+			wantRoots: map[string]string{},
+		},
+		{
+			name:      "inputs are read from stdin",
+			inputs:    []string{"-"},
+			stdin:     path("tr/framework-res") + "\n\n",
+			want:      []string{"tr/framework-res/res/values-de/plurals.xml"},
+			wantRoots: map[string]string{"tr/framework-res/res/values-de/plurals.xml": "tr/framework-res"},
+		},
+		{
+			name:      "include and exclude patterns narrow the selection",
+			inputs:    []string{path("tr")},
+			include:   []string{"values-nl/*"},
+			exclude:   []string{"arrays.xml"},
+			want:      []string{"tr/Settings.apk/res/values-nl/strings.xml"},
+			wantRoots: map[string]string{"tr/Settings.apk/res/values-nl/strings.xml": "tr"},
+		},
+		{
+			name:      "inputs without resource files are reported",
+			inputs:    []string{path("missing")},
+			wantRoots: map[string]string{},
+			warning:   "selects no resource files",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			files, roots := selectResources(test.inputs, strings.NewReader(test.stdin), test.include, test.exclude, &out)
+
+			if got := relativeFiles(t, dir, files); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got files %q, want %q", got, test.want)
+			}
+
+			gotRoots := make(map[string]string)
+			for file, root := range roots {
+				gotRoots[relativeFiles(t, dir, []string{file})[0]] = relativeFiles(t, dir, []string{root})[0]
+			}
+			if !reflect.DeepEqual(gotRoots, test.wantRoots) {
+				t.Errorf("got roots %q, want %q", gotRoots, test.wantRoots)
+			}
+
+			if test.warning == "" && out.Len() > 0 || !strings.Contains(out.String(), test.warning) {
+				t.Errorf("got output %q, want %q", out.String(), test.warning)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/redmaner/mixml/src/miuires"
+)
+
+// Validate function
+func validate() {
+
+	if argHelp {
+		showHelpFilter()
+	}
+
+	paths := cmdFilterValidate.Args()
+	if argFilterConfig != "" {
+		paths = append([]string{argFilterConfig}, paths...)
+	}
+	if len(paths) == 0 {
+		showHelpFilter()
+	}
+
+	var invalid bool
+	for _, path := range paths {
+		if _, err := loadFilterConfig(path); err != nil {
+			printConfigError(path, err)
+			invalid = true
+			continue
+		}
+		fmt.Printf("%s: OK\n", path)
+	}
+
+	if invalid {
+		os.Exit(1)
+	}
+}
+
+// Suggest function
+func suggest() {
+
+	if argHelp {
+		showHelpFilter()
+	}
+
+	var suggestions []miuires.Suggestion
+	for _, v := range findResources(argDir) {
+		res, err := miuires.NewResources(v)
+		if err != nil {
+			fmt.Printf("# An error occurred when loading %s: %v\n", v, err)
+			continue
+		}
+		suggestions = append(suggestions, res.SuggestUntranslatable()...)
+	}
+
+	data, err := miuires.SuggestFilterConfig(suggestions)
+	if err != nil {
+		fmt.Printf("# Couldn't create filter configuration: %v\n", err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(data)
+}
+
+// loadFilterConfig loads the filter configuration at path
+func loadFilterConfig(path string) (*miuires.FilterConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return miuires.GetFilterConfigFromFile(f)
+}
+
+// printConfigError prints the problems of the filter configuration at path, one per line
+func printConfigError(path string, err error) {
+	for _, problem := range strings.Split(strings.TrimPrefix(err.Error(), "yaml: unmarshal errors:\n"), "\n") {
+		fmt.Printf("%s: %s\n", path, strings.TrimSpace(problem))
+	}
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/redmaner/mixml/src/miuires"
 )
@@ -15,56 +18,224 @@ func format() {
 		showHelpFormat()
 	}
 
-	var apks []string
-	filepath.Walk(argDir, func(path string, f os.FileInfo, _ error) error {
-		if filepath.Ext(path) == ".apk" {
-			apks = append(apks, path)
-		}
-		return nil
-	})
-
-	var files []string
-	for _, v := range apks {
-		filepath.Walk(v, func(path string, f os.FileInfo, _ error) error {
-			if !f.IsDir() {
-				if f.Name() == "strings.xml" || f.Name() == "arrays.xml" || f.Name() == "plurals.xml" {
-					files = append(files, path)
-				}
-			}
-			return nil
-		})
+	applyProjectConfig(cmdFormat)
+	if err := checkLayout(argSort, argStyle); err != nil {
+		fmt.Println(err)
+		showHelpFormat()
 	}
+	files := inputFiles(cmdFormat.Args())
 
-	// Apply filter if defined
+	// Apply filter if defined, the filters of the project configuration are used
+	// when no filter configuration is passed
 	var fc *miuires.FilterConfig
 	var filter bool
 	if argFilter && argFilterConfig != "" {
-		if f, err := os.Open(argFilterConfig); err == nil {
-			defer f.Close()
-			fc, err = miuires.GetFilterConfigFromFile(f)
-			if err != nil {
-				fmt.Printf("Yaml unmarshal error: %v\n", err)
-			}
-			filter = true
-		} else {
-			fmt.Printf("Couldn't open filter configuration: %v\n", err)
+		var err error
+		if fc, err = loadFilterConfig(argFilterConfig); err != nil {
+			printConfigError(argFilterConfig, err)
+			os.Exit(1)
 		}
+		filter = true
+	} else if argFilter && len(projectFilters) > 0 {
+		var err error
+		if fc, err = miuires.GetFilterConfigFromFiles(projectFilters); err != nil {
+			printConfigError(findProjectConfig("."), err)
+			os.Exit(1)
+		}
+		filter = true
 	}
 
-	for _, v := range files {
+	// Identical translations can only be found by comparing them with the source
+	if filter && fc.FiltersIdentical() && argSource == "" {
+		fmt.Println("Warning: identical is ignored without --source")
+	}
+	if argUntranslatable && argSource == "" {
+		fmt.Println("Warning: --untranslatable is ignored without --source")
+	}
+
+	// Elements quarantined in a directory are restored into a single directory, so all
+	// files must be found in the same one
+	if argQuarantineDir != "" {
+		if err := checkQuarantineRoot(files); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	sources := newSourceCache()
+	removed := make([][]miuires.Removal, len(files))
+
+	// Options that influence the result are part of the cache fingerprint
+	c := openCache(argCache, "format", fc, argFilter, argSource, argUntranslatable, argQuarantine, argQuarantineDir, argSort, argStyle)
+
+	// formatFile formats a single file and returns its resources and source, or nil
+	// if the file was unchanged or couldn't be loaded. The removals are returned for
+	// unchanged files as well, as they were stored in the cache.
+	formatFile := func(v string, out io.Writer) (res, src *miuires.Resources, removed []miuires.Removal) {
+		var deps []string
+		if argSource != "" {
+			deps = append(deps, miuires.SourcePath(v, argSource))
+		}
+		if entry, ok := c.lookup(v, deps...); ok {
+			for _, warning := range entry.Warnings {
+				fmt.Fprintf(out, "Warning: %s\n", warning)
+			}
+			if argVerbose {
+				for _, removal := range entry.Removed {
+					fmt.Fprintln(out, removal)
+				}
+				fmt.Fprintf(out, "Unchanged %s\n", v)
+			}
+			return nil, nil, entry.Removed
+		}
+
 		res, err := miuires.NewResources(v)
 		if err != nil {
-			fmt.Printf("An error occurred when loading %s: %v\n", v, err)
-			continue
+			fmt.Fprintf(out, "An error occurred when loading %s: %v\n", v, err)
+			return nil, nil, nil
+		}
+
+		if err := res.SortKeys(argSort); err != nil {
+			fmt.Fprintf(out, "Couldn't sort %s: %v\n", v, err)
+			return nil, nil, nil
+		}
+		res.Style = argStyle
+
+		if argSource != "" {
+			src = sources.load(res.SourcePath(argSource), out)
 		}
 
 		if filter {
 			res.Filter(fc)
+			if src != nil {
+				res.FilterIdentical(fc, src)
+			}
+		}
+
+		// Remove elements that are not translatable according to the source
+		if argUntranslatable && src != nil {
+			res.FilterUntranslatable(src)
+		}
+
+		for _, warning := range res.Warnings {
+			fmt.Fprintf(out, "Warning: %s\n", warning)
+		}
+
+		if len(res.Removed) > 0 {
+			if argQuarantine || argQuarantineDir != "" {
+				if err := writeQuarantine(res); err != nil {
+					fmt.Fprintf(out, "Couldn't quarantine removed elements of %s: %v\n", v, err)
+				}
+			}
+			if argVerbose {
+				for _, removal := range res.Removed {
+					fmt.Fprintln(out, removal)
+				}
+			}
 		}
 
-		res.Write()
+		if err := res.Write(); err == nil {
+			c.store(v, res.Warnings, res.Removed, deps...)
+		}
 		if argVerbose {
-			fmt.Printf("Formatted %s\n", v)
+			fmt.Fprintf(out, "Formatted %s\n", v)
 		}
+		return res, src, res.Removed
+	}
+
+	runJobs(files, argJobs, func(index int, v string, out io.Writer) {
+		_, _, removed[index] = formatFile(v, out)
+	})
+
+	if err := c.save(); err != nil {
+		fmt.Printf("Couldn't save cache: %v\n", err)
+	}
+
+	var removals []miuires.Removal
+	for _, r := range removed {
+		removals = append(removals, r...)
+	}
+
+	// Write filter report if requested
+	if argReport != "" {
+		if err := writeReport(argReport, removals); err != nil {
+			fmt.Printf("Couldn't write filter report: %v\n", err)
+		}
+	}
+
+	// Format files again after they are saved, and print the issues found in them
+	if argWatch {
+		// Inputs are selected again to find new files, warnings were shown before
+		args := cmdFormat.Args()
+		selected := func() []string {
+			selected, roots := selectInputFiles(args, ioutil.Discard)
+			for file, root := range roots {
+				if _, ok := inputRoots[file]; !ok {
+					inputRoots[file] = root
+				}
+			}
+			return selected
+		}
+		for _, arg := range args {
+			if arg == "-" {
+				selected = func() []string { return files }
+			}
+		}
+
+		checks, err := selectChecks(argChecks)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Watching for changes, press Ctrl+C to stop")
+		watch(files, selected, func(v string) {
+			if res, src, _ := formatFile(v, os.Stdout); res != nil {
+				checkResources(res, src, checks, os.Stdout)
+			}
+			if err := c.save(); err != nil {
+				fmt.Printf("Couldn't save cache: %v\n", err)
+			}
+		})
+	}
+}
+
+// quarantineLocks serializes writes to the same quarantine file
+var quarantineLocks = newPathLocks()
+
+// writeQuarantine writes the elements removed from res to its quarantine file
+func writeQuarantine(res *miuires.Resources) error {
+	path := miuires.QuarantinePath(res.FilePath)
+	if argQuarantineDir != "" {
+		path = filepath.Join(argQuarantineDir, relativePath(res.FilePath))
+	}
+
+	unlock := quarantineLocks.lock(path)
+	defer unlock()
+	return res.WriteQuarantine(path)
+}
+
+// checkQuarantineRoot returns an error if files weren't all found in the same input
+// directory. Files that were selected directly belong to the working directory, and
+// must be inside it.
+func checkQuarantineRoot(files []string) error {
+	roots := make(map[string]bool)
+	var names []string
+	for _, file := range files {
+		root, ok := inputRoots[file]
+		if !ok {
+			root = "."
+			if _, inside := withinDir(root, file); !inside {
+				return fmt.Errorf("Can't quarantine %s in --quarantine-dir, it is outside the working directory", file)
+			}
+		}
+		if root = filepath.Clean(root); !roots[root] {
+			roots[root] = true
+			names = append(names, root)
+		}
+	}
+	if len(names) > 1 {
+		return fmt.Errorf("Can't use --quarantine-dir with files from several directories: %s", strings.Join(names, ", "))
 	}
+	return nil
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runGit runs git with args in dir and returns what it printed
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// git runs git with args in dir and returns the lines it printed
+func git(dir string, args ...string) ([]string, error) {
+	out, err := runGit(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// gitPaths runs a git command that lists paths, like diff --name-only, and returns
+// the paths. The command is run with -z, so paths aren't quoted.
+func gitPaths(dir string, command string, args ...string) ([]string, error) {
+	out, err := runGit(dir, append([]string{command, "-z"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, path := range strings.Split(out, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// gitTopLevel returns the top level directory of the git repository containing dir
+func gitTopLevel(dir string) (string, error) {
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	if len(top) == 0 {
+		return "", fmt.Errorf("%s is not in a git repository", dir)
+	}
+	return filepath.FromSlash(top[0]), nil
+}
+
+// gitChangedFiles returns the absolute paths of the files in the git repository at
+// top that changed since ref. Changes in the working tree, staged changes and
+// untracked files are included.
+func gitChangedFiles(top string, ref string) (map[string]bool, error) {
+
+	changed, err := gitPaths(top, "diff", "--name-only", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitPaths(top, "ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]bool)
+	for _, name := range append(changed, untracked...) {
+		files[filepath.Join(top, filepath.FromSlash(name))] = true
+	}
+	return files, nil
+}
+
+// filterChanged returns the files that changed since ref in the git repository they
+// are in. Files may be spread over several repositories, each is queried once.
+func filterChanged(files []string, ref string) (selected []string, err error) {
+
+	tops := make(map[string]string)
+	changes := make(map[string]map[string]bool)
+
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = resolved
+		}
+
+		dir := filepath.Dir(abs)
+		top, ok := tops[dir]
+		if !ok {
+			if top, err = gitTopLevel(dir); err != nil {
+				return nil, err
+			}
+			tops[dir] = top
+		}
+
+		changed, ok := changes[top]
+		if !ok {
+			if changed, err = gitChangedFiles(top, ref); err != nil {
+				return nil, err
+			}
+			changes[top] = changed
+		}
+
+		if changed[abs] {
+			selected = append(selected, file)
+		}
+	}
+	return selected, nil
+}
@@ -13,25 +13,144 @@ Usage:
 
 Commands:
     format             Format MIUI resources
+    check              Check MIUI resources for errors
+    restore            Restore quarantined elements
+    filter             Manage filter configurations
+    hook               Manage the git pre-commit hook
     help               Show this help
 
+Default options can be set in a .mixml.yaml file in the working directory or
+one of its parents, options passed on the command line override them.
+
 `
 
 const helpMessageFormat = `
 mixml version: %s (by redmaner)
 
 Usage:
-    mixml format <options>
+    mixml format <options> [files, directories or patterns, - reads them from stdin]
 
 Options:
-    --dir     | -d      Path of directory to format
+    --dir     | -d      Path of directory to format, can be passed more than once
+    --include | -i      Only format files matching this pattern, like values-nl/*
+    --exclude | -x      Skip files matching this pattern
+    --changed-since | -g
+                        Only format files changed since this git ref, including
+                        uncommitted and untracked files
     --filter  | -f      Enable filter when formatting
     --config  | -c      Path to the filter configuration YAML file
+    --source  | -s      Path of directory with the untranslated source resources
+    --untranslatable | -u
+                        Remove elements marked translatable="false" in the source
+    --report  | -r      Path to write the removed elements to (.json or .csv)
+    --quarantine | -q   Write removed elements to sibling files, like strings.filtered.xml
+    --quarantine-dir | -Q
+                        Write removed elements to this directory instead
+    --jobs    | -j      Number of files to process in parallel (default: 1,
+                        0 uses the number of CPUs)
+    --cache   | -C      Path to a cache file, files that didn't change since
+                        the previous run are skipped
+    --sort    | -o      Order of the elements, key or file to keep the order
+                        of the file (default: key)
+    --style   | -t      Indentation style, spaces, compact for two spaces or
+                        tabs (default: spaces)
+    --watch   | -w      Keep watching the files after formatting them, files
+                        are formatted and checked again when they are saved
     --verbose | -v      Show verbose logging
     --help    | -h      Show this help
 
 `
 
+const helpMessageCheck = `
+mixml version: %s (by redmaner)
+
+Usage:
+    mixml check <options> [files, directories or patterns, - reads them from stdin]
+
+Options:
+    --dir     | -d      Path of directory to check, can be passed more than once
+    --include | -i      Only check files matching this pattern, like values-nl/*
+    --exclude | -x      Skip files matching this pattern
+    --changed-since | -g
+                        Only check files changed since this git ref, including
+                        uncommitted and untracked files
+    --source  | -s      Path of directory with the untranslated source resources
+    --checks  | -k      Comma separated list of checks to run (default: all)
+    --jobs    | -j      Number of files to process in parallel (default: 1,
+                        0 uses the number of CPUs)
+    --cache   | -C      Path to a cache file, files that didn't change since
+                        the previous run are skipped
+    --verbose | -v      Show verbose logging
+    --help    | -h      Show this help
+
+Checks:
+    arrays             Array item counts match the source and sibling arrays
+    integer-arrays     Integer-array items are integers or @integer/ references
+    markup             Inline markup is balanced, known and matches the source
+    xliff              Every xliff:g placeholder of the source is kept exactly once
+
+`
+
+const helpMessageRestore = `
+mixml version: %s (by redmaner)
+
+Usage:
+    mixml restore <options>
+
+Options:
+    --dir     | -d      Path of directory to restore quarantined elements in
+    --quarantine-dir | -Q
+                        Path of directory with quarantined elements, when
+                        they are not stored in sibling files
+    --verbose | -v      Show verbose logging
+    --help    | -h      Show this help
+
+`
+
+const helpMessageFilter = `
+mixml version: %s (by redmaner)
+
+Usage:
+    mixml filter <command> <options>
+
+Commands:
+    validate           Validate filter configurations
+    suggest            Suggest filter rules for likely untranslatable elements
+
+Options for validate:
+    --config  | -c      Path to the filter configuration YAML file, more
+                        files can be passed as arguments
+    --help    | -h      Show this help
+
+Options for suggest:
+    --dir     | -d      Path of directory to scan
+    --help    | -h      Show this help
+
+`
+
+const helpMessageHook = `
+mixml version: %s (by redmaner)
+
+Usage:
+    mixml hook <command> <options> [-- options for format or check]
+
+Commands:
+    install            Install a git pre-commit hook that runs mixml
+    run                Format or check the staged resource files, this is
+                       what the pre-commit hook runs
+
+Options for install:
+    --mode    | -m      Run format, which stages the formatted files again,
+                        or check, which doesn't change files (default: format)
+    --force   | -F      Overwrite an existing pre-commit hook
+    --help    | -h      Show this help
+
+Options for run:
+    --mode    | -m      Run format or check (default: format)
+    --help    | -h      Show this help
+
+`
+
 func showHelp() {
 	fmt.Printf(helpMessage, version)
 	os.Exit(10)
@@ -41,3 +160,23 @@ func showHelpFormat() {
 	fmt.Printf(helpMessageFormat, version)
 	os.Exit(10)
 }
+
+func showHelpCheck() {
+	fmt.Printf(helpMessageCheck, version)
+	os.Exit(10)
+}
+
+func showHelpRestore() {
+	fmt.Printf(helpMessageRestore, version)
+	os.Exit(10)
+}
+
+func showHelpFilter() {
+	fmt.Printf(helpMessageFilter, version)
+	os.Exit(10)
+}
+
+func showHelpHook() {
+	fmt.Printf(helpMessageHook, version)
+	os.Exit(10)
+}
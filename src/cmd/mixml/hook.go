@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Modes of the pre-commit hook
+const (
+	hookModeFormat = "format"
+	hookModeCheck  = "check"
+)
+
+// hookMarker identifies pre-commit hooks that were installed by mixml
+const hookMarker = "# mixml pre-commit hook"
+
+// Install function
+func hookInstall() {
+
+	if argHelp {
+		showHelpHook()
+	}
+	if argHookMode != hookModeFormat && argHookMode != hookModeCheck {
+		fmt.Printf("Unknown hook mode %q, use format or check\n", argHookMode)
+		os.Exit(1)
+	}
+
+	paths, err := git(".", "rev-parse", "--git-path", "hooks/pre-commit")
+	if err != nil || len(paths) == 0 {
+		fmt.Printf("Couldn't find the git hooks directory: %v\n", err)
+		os.Exit(1)
+	}
+	path := paths[0]
+
+	// Don't overwrite hooks that weren't installed by mixml
+	if data, err := ioutil.ReadFile(path); err == nil && !strings.Contains(string(data), hookMarker) && !argForce {
+		fmt.Printf("%s already exists, use --force to overwrite it\n", path)
+		os.Exit(1)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		executable = "mixml"
+	}
+
+	command := []string{executable, "hook", "run", "--mode", argHookMode}
+	if options := cmdHookInstall.Args(); len(options) > 0 {
+		command = append(append(command, "--"), options...)
+	}
+	for i := range command {
+		command[i] = shellQuote(command[i])
+	}
+	hook := fmt.Sprintf("#!/bin/sh\n%s, installed by mixml hook install\nexec %s\n", hookMarker, strings.Join(command, " "))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Printf("Couldn't create the git hooks directory: %v\n", err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(path, []byte(hook), 0755); err != nil {
+		fmt.Printf("Couldn't write %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Installed %s pre-commit hook in %s\n", argHookMode, path)
+}
+
+// Run function. The staged resource files are formatted and staged again, or checked
+// without changing them. The remaining arguments are options for format or check.
+func hookRun() {
+
+	if argHelp {
+		showHelpHook()
+	}
+
+	staged, partial, err := gitStagedFiles()
+	if err != nil {
+		fmt.Printf("Couldn't determine staged files: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Files with unstaged changes would be formatted or checked with those changes,
+	// which aren't part of the commit
+	if len(partial) > 0 {
+		for _, path := range partial {
+			fmt.Printf("%s: has unstaged changes\n", path)
+		}
+		fmt.Println("Stage or stash the unstaged changes of these files before committing")
+		os.Exit(1)
+	}
+	if len(staged) == 0 {
+		return
+	}
+	args := append(cmdHookRun.Args(), staged...)
+
+	switch argHookMode {
+	case hookModeFormat:
+		if err := cmdFormat.Parse(args); err != nil {
+			fmt.Println(err)
+			showHelpHook()
+		}
+		format()
+
+		// Stage the formatted files again
+		if _, err := git(".", append([]string{"add", "--"}, staged...)...); err != nil {
+			fmt.Printf("Couldn't stage formatted files: %v\n", err)
+			os.Exit(1)
+		}
+	case hookModeCheck:
+		if err := cmdCheck.Parse(args); err != nil {
+			fmt.Println(err)
+			showHelpHook()
+		}
+		check()
+	default:
+		fmt.Printf("Unknown hook mode %q, use format or check\n", argHookMode)
+		os.Exit(1)
+	}
+}
+
+// gitStagedFiles returns the staged strings.xml, arrays.xml and plurals.xml files,
+// relative to the current directory. Deleted files are left out. partial holds the
+// staged files that also have unstaged changes.
+func gitStagedFiles() (staged []string, partial []string, err error) {
+
+	top, err := gitTopLevel(".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names, err := gitPaths(top, "diff", "--cached", "--name-only", "--diff-filter=ACMR")
+	if err != nil {
+		return nil, nil, err
+	}
+	unstaged, err := gitPaths(top, "diff", "--name-only")
+	if err != nil {
+		return nil, nil, err
+	}
+	changed := make(map[string]bool)
+	for _, name := range unstaged {
+		changed[name] = true
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, name := range names {
+		path := filepath.Join(top, filepath.FromSlash(name))
+		if !isResourceFile(filepath.Base(path)) {
+			continue
+		}
+		if rel, err := filepath.Rel(wd, path); err == nil {
+			path = rel
+		}
+		staged = append(staged, path)
+		if changed[name] {
+			partial = append(partial, path)
+		}
+	}
+	return staged, partial, nil
+}
+
+// shellQuote quotes s for use in a POSIX shell script
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
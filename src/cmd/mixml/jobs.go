@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/redmaner/mixml/src/miuires"
+)
+
+// runJobs calls fn for every file using a pool of workers. When jobs is smaller than
+// one, the number of CPUs is used. Output written to out by fn is printed in the
+// order of files, so logs are deterministic regardless of the number of workers.
+func runJobs(files []string, jobs int, fn func(index int, path string, out io.Writer)) {
+
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+
+	outputs := make([]bytes.Buffer, len(files))
+	done := make([]chan struct{}, len(files))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	indexes := make(chan int)
+	for w := 0; w < jobs; w++ {
+		go func() {
+			for i := range indexes {
+				fn(i, files[i], &outputs[i])
+				close(done[i])
+			}
+		}()
+	}
+
+	go func() {
+		for i := range files {
+			indexes <- i
+		}
+		close(indexes)
+	}()
+
+	for i := range files {
+		<-done[i]
+		os.Stdout.Write(outputs[i].Bytes())
+	}
+}
+
+// sourceCache holds source resources, which are shared by all languages so they
+// are loaded only once. It is safe for concurrent use.
+type sourceCache struct {
+	sync.Mutex
+	sources map[string]*miuires.Resources
+}
+
+// newSourceCache returns a new empty sourceCache
+func newSourceCache() *sourceCache {
+	return &sourceCache{sources: make(map[string]*miuires.Resources)}
+}
+
+// load loads the source resources at path, or returns them from the cache if they
+// were loaded before. It returns nil if the source doesn't exist. The returned
+// resources must not be modified.
+func (sc *sourceCache) load(path string, out io.Writer) *miuires.Resources {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if src, ok := sc.sources[path]; ok {
+		return src
+	}
+
+	var src *miuires.Resources
+	if _, err := os.Stat(path); err == nil {
+		if src, err = miuires.NewResources(path); err != nil {
+			fmt.Fprintf(out, "An error occurred when loading source %s: %v\n", path, err)
+		}
+	}
+	sc.sources[path] = src
+	return src
+}
+
+// pathLocks holds a lock per path. It is safe for concurrent use.
+type pathLocks struct {
+	sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// newPathLocks returns new pathLocks
+func newPathLocks() *pathLocks {
+	return &pathLocks{locks: make(map[string]*sync.Mutex)}
+}
+
+// lock locks path and returns the function that unlocks it
+func (pl *pathLocks) lock(path string) func() {
+	path = filepath.Clean(path)
+
+	pl.Lock()
+	l, ok := pl.locks[path]
+	if !ok {
+		l = &sync.Mutex{}
+		pl.locks[path] = l
+	}
+	pl.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
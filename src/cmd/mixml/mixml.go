@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+
+	"github.com/redmaner/mixml/src/miuires"
 )
 
 const version = "r6"
@@ -11,31 +13,133 @@ const version = "r6"
 // Commands
 var cmdFormat = flag.NewFlagSet("format", flag.ExitOnError)
 var cmdCheck = flag.NewFlagSet("check", flag.ExitOnError)
+var cmdRestore = flag.NewFlagSet("restore", flag.ExitOnError)
+var cmdFilterValidate = flag.NewFlagSet("filter validate", flag.ExitOnError)
+var cmdFilterSuggest = flag.NewFlagSet("filter suggest", flag.ExitOnError)
+var cmdHookInstall = flag.NewFlagSet("hook install", flag.ExitOnError)
+var cmdHookRun = flag.NewFlagSet("hook run", flag.ExitOnError)
 
 // Arguments
 var argDir string
+var argDirs stringList
+var argInclude stringList
+var argExclude stringList
 var argFilter bool
 var argFilterConfig string
+var argSource string
+var argReport string
+var argQuarantine bool
+var argUntranslatable bool
+var argQuarantineDir string
+var argChecks string
 var argVerbose bool
+var argJobs int
+var argCache string
+var argChangedSince string
+var argWatch bool
+var argSort string
+var argStyle string
+var argHookMode string
+var argForce bool
 var argHelp bool
 
 func init() {
 
 	// Arguments for format
-	cmdFormat.StringVar(&argDir, "dir", "./", "Directory of MIUI resources")
-	cmdFormat.StringVar(&argDir, "d", "./", "Directory of MIUI resources")
+	cmdFormat.Var(&argDirs, "dir", "Directory of MIUI resources, can be passed more than once")
+	cmdFormat.Var(&argDirs, "d", "Directory of MIUI resources, can be passed more than once")
+	cmdFormat.Var(&argInclude, "include", "Only process files matching this pattern")
+	cmdFormat.Var(&argInclude, "i", "Only process files matching this pattern")
+	cmdFormat.Var(&argExclude, "exclude", "Skip files matching this pattern")
+	cmdFormat.Var(&argExclude, "x", "Skip files matching this pattern")
+	cmdFormat.StringVar(&argChangedSince, "changed-since", "", "Only process files changed since this git ref")
+	cmdFormat.StringVar(&argChangedSince, "g", "", "Only process files changed since this git ref")
 	cmdFormat.BoolVar(&argFilter, "filter", false, "Filter MIUI resources")
 	cmdFormat.BoolVar(&argFilter, "f", false, "Filter MIUI resources")
 	cmdFormat.StringVar(&argFilterConfig, "config", "", "Path to filter configuration")
 	cmdFormat.StringVar(&argFilterConfig, "c", "", "Path to filter configuration")
+	cmdFormat.StringVar(&argSource, "source", "", "Directory of untranslated MIUI source resources")
+	cmdFormat.StringVar(&argSource, "s", "", "Directory of untranslated MIUI source resources")
+	cmdFormat.BoolVar(&argUntranslatable, "untranslatable", false, "Remove elements marked untranslatable in the source")
+	cmdFormat.BoolVar(&argUntranslatable, "u", false, "Remove elements marked untranslatable in the source")
+	cmdFormat.StringVar(&argReport, "report", "", "Path to write the filter report to (.json or .csv)")
+	cmdFormat.StringVar(&argReport, "r", "", "Path to write the filter report to (.json or .csv)")
+	cmdFormat.BoolVar(&argQuarantine, "quarantine", false, "Quarantine removed elements in sibling files")
+	cmdFormat.BoolVar(&argQuarantine, "q", false, "Quarantine removed elements in sibling files")
+	cmdFormat.StringVar(&argQuarantineDir, "quarantine-dir", "", "Directory to quarantine removed elements in")
+	cmdFormat.StringVar(&argQuarantineDir, "Q", "", "Directory to quarantine removed elements in")
+	cmdFormat.IntVar(&argJobs, "jobs", 1, "Number of files to process in parallel, 0 for the number of CPUs")
+	cmdFormat.IntVar(&argJobs, "j", 1, "Number of files to process in parallel, 0 for the number of CPUs")
+	cmdFormat.StringVar(&argCache, "cache", "", "Path to the cache of unchanged files")
+	cmdFormat.StringVar(&argCache, "C", "", "Path to the cache of unchanged files")
+	cmdFormat.StringVar(&argSort, "sort", miuires.SortKey, "Order of the elements, key or file")
+	cmdFormat.StringVar(&argSort, "o", miuires.SortKey, "Order of the elements, key or file")
+	cmdFormat.StringVar(&argStyle, "style", miuires.StyleSpaces, "Indentation style, spaces, compact or tabs")
+	cmdFormat.StringVar(&argStyle, "t", miuires.StyleSpaces, "Indentation style, spaces, compact or tabs")
+	cmdFormat.BoolVar(&argWatch, "watch", false, "Format files again when they are saved")
+	cmdFormat.BoolVar(&argWatch, "w", false, "Format files again when they are saved")
 	cmdFormat.BoolVar(&argHelp, "help", false, "Show help")
 	cmdFormat.BoolVar(&argHelp, "h", false, "Show help")
 	cmdFormat.BoolVar(&argVerbose, "verbose", false, "Print verbose logging")
 	cmdFormat.BoolVar(&argVerbose, "v", false, "Print verbose logging")
 
 	// Arguments for check
-	cmdCheck.StringVar(&argDir, "dir", "./", "Directory of MIUI resources")
-	cmdCheck.StringVar(&argDir, "d", "./", "Directory of MIUI resources")
+	cmdCheck.Var(&argDirs, "dir", "Directory of MIUI resources, can be passed more than once")
+	cmdCheck.Var(&argDirs, "d", "Directory of MIUI resources, can be passed more than once")
+	cmdCheck.Var(&argInclude, "include", "Only process files matching this pattern")
+	cmdCheck.Var(&argInclude, "i", "Only process files matching this pattern")
+	cmdCheck.Var(&argExclude, "exclude", "Skip files matching this pattern")
+	cmdCheck.Var(&argExclude, "x", "Skip files matching this pattern")
+	cmdCheck.StringVar(&argChangedSince, "changed-since", "", "Only process files changed since this git ref")
+	cmdCheck.StringVar(&argChangedSince, "g", "", "Only process files changed since this git ref")
+	cmdCheck.StringVar(&argSource, "source", "", "Directory of untranslated MIUI source resources")
+	cmdCheck.StringVar(&argSource, "s", "", "Directory of untranslated MIUI source resources")
+	cmdCheck.StringVar(&argChecks, "checks", "", "Comma separated list of checks to run")
+	cmdCheck.StringVar(&argChecks, "k", "", "Comma separated list of checks to run")
+	cmdCheck.IntVar(&argJobs, "jobs", 1, "Number of files to process in parallel, 0 for the number of CPUs")
+	cmdCheck.IntVar(&argJobs, "j", 1, "Number of files to process in parallel, 0 for the number of CPUs")
+	cmdCheck.StringVar(&argCache, "cache", "", "Path to the cache of unchanged files")
+	cmdCheck.StringVar(&argCache, "C", "", "Path to the cache of unchanged files")
+	cmdCheck.BoolVar(&argHelp, "help", false, "Show help")
+	cmdCheck.BoolVar(&argHelp, "h", false, "Show help")
+	cmdCheck.BoolVar(&argVerbose, "verbose", false, "Print verbose logging")
+	cmdCheck.BoolVar(&argVerbose, "v", false, "Print verbose logging")
+
+	// Arguments for restore
+	cmdRestore.StringVar(&argDir, "dir", "./", "Directory of MIUI resources")
+	cmdRestore.StringVar(&argDir, "d", "./", "Directory of MIUI resources")
+	cmdRestore.StringVar(&argQuarantineDir, "quarantine-dir", "", "Directory with quarantined elements")
+	cmdRestore.StringVar(&argQuarantineDir, "Q", "", "Directory with quarantined elements")
+	cmdRestore.BoolVar(&argHelp, "help", false, "Show help")
+	cmdRestore.BoolVar(&argHelp, "h", false, "Show help")
+	cmdRestore.BoolVar(&argVerbose, "verbose", false, "Print verbose logging")
+	cmdRestore.BoolVar(&argVerbose, "v", false, "Print verbose logging")
+
+	// Arguments for filter validate
+	cmdFilterValidate.StringVar(&argFilterConfig, "config", "", "Path to filter configuration")
+	cmdFilterValidate.StringVar(&argFilterConfig, "c", "", "Path to filter configuration")
+	cmdFilterValidate.BoolVar(&argHelp, "help", false, "Show help")
+	cmdFilterValidate.BoolVar(&argHelp, "h", false, "Show help")
+
+	// Arguments for filter suggest
+	cmdFilterSuggest.StringVar(&argDir, "dir", "./", "Directory of MIUI resources")
+	cmdFilterSuggest.StringVar(&argDir, "d", "./", "Directory of MIUI resources")
+	cmdFilterSuggest.BoolVar(&argHelp, "help", false, "Show help")
+	cmdFilterSuggest.BoolVar(&argHelp, "h", false, "Show help")
+
+	// Arguments for hook install
+	cmdHookInstall.StringVar(&argHookMode, "mode", hookModeFormat, "Run format or check in the hook")
+	cmdHookInstall.StringVar(&argHookMode, "m", hookModeFormat, "Run format or check in the hook")
+	cmdHookInstall.BoolVar(&argForce, "force", false, "Overwrite an existing pre-commit hook")
+	cmdHookInstall.BoolVar(&argForce, "F", false, "Overwrite an existing pre-commit hook")
+	cmdHookInstall.BoolVar(&argHelp, "help", false, "Show help")
+	cmdHookInstall.BoolVar(&argHelp, "h", false, "Show help")
+
+	// Arguments for hook run
+	cmdHookRun.StringVar(&argHookMode, "mode", hookModeFormat, "Run format or check")
+	cmdHookRun.StringVar(&argHookMode, "m", hookModeFormat, "Run format or check")
+	cmdHookRun.BoolVar(&argHelp, "help", false, "Show help")
+	cmdHookRun.BoolVar(&argHelp, "h", false, "Show help")
 }
 
 func main() {
@@ -53,6 +157,57 @@ func main() {
 		}
 		format()
 	case "check":
+		if err := cmdCheck.Parse(args[2:]); err != nil {
+			fmt.Println(err)
+			showHelp()
+		}
+		check()
+	case "restore":
+		if err := cmdRestore.Parse(args[2:]); err != nil {
+			fmt.Println(err)
+			showHelp()
+		}
+		restore()
+	case "filter":
+		if len(args) < 3 {
+			showHelpFilter()
+		}
+		switch args[2] {
+		case "validate":
+			if err := cmdFilterValidate.Parse(args[3:]); err != nil {
+				fmt.Println(err)
+				showHelpFilter()
+			}
+			validate()
+		case "suggest":
+			if err := cmdFilterSuggest.Parse(args[3:]); err != nil {
+				fmt.Println(err)
+				showHelpFilter()
+			}
+			suggest()
+		default:
+			showHelpFilter()
+		}
+	case "hook":
+		if len(args) < 3 {
+			showHelpHook()
+		}
+		switch args[2] {
+		case "install":
+			if err := cmdHookInstall.Parse(args[3:]); err != nil {
+				fmt.Println(err)
+				showHelpHook()
+			}
+			hookInstall()
+		case "run":
+			if err := cmdHookRun.Parse(args[3:]); err != nil {
+				fmt.Println(err)
+				showHelpHook()
+			}
+			hookRun()
+		default:
+			showHelpHook()
+		}
 	default:
 		showHelp()
 	}
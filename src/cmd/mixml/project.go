@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/redmaner/mixml/src/miuires"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// projectConfigName is the name of the project configuration file
+const projectConfigName = ".mixml.yaml"
+
+// projectConfig holds the default options of a project. Relative paths are relative
+// to the directory of the configuration file.
+type projectConfig struct {
+	Dirs    []string `yaml:"dirs"`
+	Filters []string `yaml:"filters"`
+	Source  string   `yaml:"source"`
+	Checks  []string `yaml:"checks"`
+	Sort    string   `yaml:"sort"`
+	Style   string   `yaml:"style"`
+}
+
+// projectFilters holds the filter configurations of the project configuration, which
+// are used when --config isn't passed
+var projectFilters []string
+
+// findProjectConfig returns the path of the project configuration in dir or the
+// closest of its parents, or an empty string if there is none
+func findProjectConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		if f, err := os.Stat(path); err == nil && !f.IsDir() {
+			return path
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadProjectConfig returns the validated project configuration at path, with
+// relative paths resolved
+func loadProjectConfig(path string) (*projectConfig, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown fields are not allowed
+	var pc projectConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&pc); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if _, err := selectChecks(strings.Join(pc.Checks, ",")); err != nil {
+		return nil, err
+	}
+	if err := checkLayout(pc.Sort, pc.Style); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	for i := range pc.Dirs {
+		pc.Dirs[i] = projectPath(dir, pc.Dirs[i])
+	}
+	for i := range pc.Filters {
+		pc.Filters[i] = projectPath(dir, pc.Filters[i])
+	}
+	if pc.Source != "" {
+		pc.Source = projectPath(dir, pc.Source)
+	}
+	return &pc, nil
+}
+
+// checkLayout returns an error if sort or style are unknown. Empty values are allowed.
+func checkLayout(sort string, style string) error {
+	switch sort {
+	case "", miuires.SortKey, miuires.SortFile:
+	default:
+		return fmt.Errorf("unknown sort mode %q, use %s or %s", sort, miuires.SortKey, miuires.SortFile)
+	}
+	switch style {
+	case "", miuires.StyleSpaces, miuires.StyleCompact, miuires.StyleTabs:
+	default:
+		return fmt.Errorf("unknown style %q, use %s, %s or %s", style, miuires.StyleSpaces, miuires.StyleCompact, miuires.StyleTabs)
+	}
+	return nil
+}
+
+// projectPath returns path resolved against dir, relative to the working directory
+// if possible
+func projectPath(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	path = filepath.Join(dir, path)
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil {
+			return rel
+		}
+	}
+	return path
+}
+
+// applyProjectConfig uses the project configuration found in the working directory or
+// its parents for the options that weren't passed to the command of fs. Directories
+// are only used when no files or directories were passed.
+func applyProjectConfig(fs *flag.FlagSet) {
+
+	path := findProjectConfig(".")
+	if path == "" {
+		return
+	}
+
+	pc, err := loadProjectConfig(path)
+	if err != nil {
+		printConfigError(path, err)
+		os.Exit(1)
+	}
+	if argVerbose {
+		fmt.Printf("Using project configuration %s\n", path)
+	}
+
+	passed := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+	})
+	isPassed := func(name, short string) bool {
+		return passed[name] || passed[short]
+	}
+	defined := func(name string) bool {
+		return fs.Lookup(name) != nil
+	}
+
+	if defined("dir") && !isPassed("dir", "d") && fs.NArg() == 0 {
+		argDirs = pc.Dirs
+	}
+	if defined("config") && !isPassed("config", "c") && len(pc.Filters) > 0 {
+		projectFilters = pc.Filters
+		if !isPassed("filter", "f") {
+			argFilter = true
+		}
+	}
+	if defined("source") && !isPassed("source", "s") && pc.Source != "" {
+		argSource = pc.Source
+	}
+
+	// Checks are also used by format, which runs them in watch mode
+	if !isPassed("checks", "k") && len(pc.Checks) > 0 {
+		argChecks = strings.Join(pc.Checks, ",")
+	}
+	if defined("sort") && !isPassed("sort", "o") && pc.Sort != "" {
+		argSort = pc.Sort
+	}
+	if defined("style") && !isPassed("style", "t") && pc.Style != "" {
+		argStyle = pc.Style
+	}
+}
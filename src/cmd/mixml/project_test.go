@@ -0,0 +1,228 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/redmaner/mixml/src/miuires"
+)
+
+// writeTestProject writes the project configuration to a new temporary directory,
+// changes the working directory to its subdirectory sub and returns a function that
+// restores the working directory
+func writeTestProject(t *testing.T, config string) func() {
+	dir, err := ioutil.TempDir("", "mixml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, projectConfigName), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLoadProjectConfig(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		config  string
+		want    *projectConfig
+		wantErr bool
+	}{
+		{
+			name:   "empty",
+			config: "",
+			want:   &projectConfig{},
+		},
+		{
+			name: "relative paths",
+			config: "dirs: [res, /abs/res]\nfilters: [filter.yaml]\nsource: values\n" +
+				"checks: [arrays, markup]\nsort: file\nstyle: tabs\n",
+			want: &projectConfig{
+				Dirs:    []string{filepath.Join("..", "res"), "/abs/res"},
+				Filters: []string{filepath.Join("..", "filter.yaml")},
+				Source:  filepath.Join("..", "values"),
+				Checks:  []string{"arrays", "markup"},
+				Sort:    miuires.SortFile,
+				Style:   miuires.StyleTabs,
+			},
+		},
+		{name: "unknown field", config: "dir: res\n", wantErr: true},
+		{name: "unknown check", config: "checks: [spelling]\n", wantErr: true},
+		{name: "unknown sort", config: "sort: value\n", wantErr: true},
+		{name: "unknown style", config: "style: wide\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		restore := writeTestProject(t, tt.config)
+		pc, err := loadProjectConfig(filepath.Join("..", projectConfigName))
+		restore()
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(pc, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, pc, tt.want)
+		}
+	}
+}
+
+// newTestFlagSet returns a flag set with the options of format, or of check if format
+// is false, bound to the global arguments
+func newTestFlagSet(format bool) *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&argDirs, "dir", "")
+	fs.Var(&argDirs, "d", "")
+	fs.StringVar(&argSource, "source", "", "")
+	fs.StringVar(&argSource, "s", "", "")
+	if format {
+		fs.BoolVar(&argFilter, "filter", false, "")
+		fs.BoolVar(&argFilter, "f", false, "")
+		fs.StringVar(&argFilterConfig, "config", "", "")
+		fs.StringVar(&argFilterConfig, "c", "", "")
+		fs.StringVar(&argSort, "sort", miuires.SortKey, "")
+		fs.StringVar(&argSort, "o", miuires.SortKey, "")
+		fs.StringVar(&argStyle, "style", miuires.StyleSpaces, "")
+		fs.StringVar(&argStyle, "t", miuires.StyleSpaces, "")
+	} else {
+		fs.StringVar(&argChecks, "checks", "", "")
+		fs.StringVar(&argChecks, "k", "", "")
+	}
+	return fs
+}
+
+func TestApplyProjectConfig(t *testing.T) {
+
+	config := "dirs: [res]\nfilters: [filter.yaml]\nsource: values\nchecks: [arrays]\nsort: file\nstyle: tabs\n"
+
+	type result struct {
+		Dirs    []string
+		Filters []string
+		Filter  bool
+		Source  string
+		Checks  string
+		Sort    string
+		Style   string
+	}
+
+	tests := []struct {
+		name   string
+		format bool
+		args   []string
+		want   result
+	}{
+		{
+			name:   "format defaults",
+			format: true,
+			want: result{
+				Dirs:    []string{filepath.Join("..", "res")},
+				Filters: []string{filepath.Join("..", "filter.yaml")},
+				Filter:  true,
+				Source:  filepath.Join("..", "values"),
+				Checks:  "arrays",
+				Sort:    miuires.SortFile,
+				Style:   miuires.StyleTabs,
+			},
+		},
+		{
+			name:   "format flags win",
+			format: true,
+			args:   []string{"-d", "other", "-c", "mine.yaml", "-s", "values-en", "-o", "key", "--style", "compact"},
+			want: result{
+				Dirs:   []string{"other"},
+				Source: "values-en",
+				Checks: "arrays",
+				Sort:   miuires.SortKey,
+				Style:  miuires.StyleCompact,
+			},
+		},
+		{
+			name:   "arguments replace dirs",
+			format: true,
+			args:   []string{"app.apk"},
+			want: result{
+				Filters: []string{filepath.Join("..", "filter.yaml")},
+				Filter:  true,
+				Source:  filepath.Join("..", "values"),
+				Checks:  "arrays",
+				Sort:    miuires.SortFile,
+				Style:   miuires.StyleTabs,
+			},
+		},
+		{
+			name:   "filter flag kept",
+			format: true,
+			args:   []string{"-f=false"},
+			want: result{
+				Dirs:    []string{filepath.Join("..", "res")},
+				Filters: []string{filepath.Join("..", "filter.yaml")},
+				Source:  filepath.Join("..", "values"),
+				Checks:  "arrays",
+				Sort:    miuires.SortFile,
+				Style:   miuires.StyleTabs,
+			},
+		},
+		{
+			name: "check defaults",
+			want: result{
+				Dirs:   []string{filepath.Join("..", "res")},
+				Source: filepath.Join("..", "values"),
+				Checks: "arrays",
+			},
+		},
+		{
+			name: "check flags win",
+			args: []string{"--checks", "markup"},
+			want: result{
+				Dirs:   []string{filepath.Join("..", "res")},
+				Source: filepath.Join("..", "values"),
+				Checks: "markup",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		argDirs, projectFilters, argFilter, argSource, argChecks, argSort, argStyle = nil, nil, false, "", "", "", ""
+
+		fs := newTestFlagSet(tt.format)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+
+		restore := writeTestProject(t, config)
+		applyProjectConfig(fs)
+		restore()
+
+		got := result{
+			Dirs:    argDirs,
+			Filters: projectFilters,
+			Filter:  argFilter,
+			Source:  argSource,
+			Checks:  argChecks,
+			Sort:    argSort,
+			Style:   argStyle,
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/redmaner/mixml/src/miuires"
+)
+
+// writeReport writes the removals to path. The format is determined by the file
+// extension, which is either .json or .csv
+func writeReport(path string, removals []miuires.Removal) error {
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch filepath.Ext(path) {
+	case ".json":
+		if removals == nil {
+			removals = []miuires.Removal{}
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(removals)
+
+	case ".csv":
+		w := csv.NewWriter(f)
+		w.Write([]string{"file", "key", "section", "app", "locale", "match", "mode"})
+		for _, r := range removals {
+			w.Write([]string{r.FilePath, r.Key, r.Section, r.App, r.Locale, r.Match, r.Mode})
+		}
+		w.Flush()
+		return w.Error()
+	}
+	return fmt.Errorf("unsupported report format %s, use .json or .csv", filepath.Ext(path))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/redmaner/mixml/src/miuires"
+)
+
+// Restore function
+func restore() {
+
+	if argHelp {
+		showHelpRestore()
+	}
+
+	// Map quarantine files to the resources they belong to
+	targets := make(map[string]string)
+	var quarantined []string
+	if argQuarantineDir == "" {
+		filepath.Walk(argDir, func(path string, f os.FileInfo, _ error) error {
+			if f != nil && !f.IsDir() && miuires.IsQuarantinePath(path) {
+				quarantined = append(quarantined, path)
+				targets[path] = miuires.QuarantineTarget(path)
+			}
+			return nil
+		})
+	} else {
+		// Every resource file is restored, including those of files that weren't in an
+		// application directory
+		filepath.Walk(argQuarantineDir, func(path string, f os.FileInfo, _ error) error {
+			if f == nil || f.IsDir() || !isResourceFile(f.Name()) {
+				return nil
+			}
+			if rel, err := filepath.Rel(argQuarantineDir, path); err == nil {
+				quarantined = append(quarantined, path)
+				targets[path] = filepath.Join(argDir, rel)
+			}
+			return nil
+		})
+	}
+
+	for _, v := range quarantined {
+		quarantine, err := miuires.NewResources(v)
+		if err != nil {
+			fmt.Printf("An error occurred when loading %s: %v\n", v, err)
+			continue
+		}
+
+		// Restore into existing resources, or recreate them if they were removed
+		target := targets[v]
+		res := quarantine
+		restored := len(quarantine.Elements)
+		if _, err := os.Stat(target); err == nil {
+			if res, err = miuires.NewResources(target); err != nil {
+				fmt.Printf("An error occurred when loading %s: %v\n", target, err)
+				continue
+			}
+			restored = res.Restore(quarantine)
+		}
+
+		res.FilePath = target
+		if err := res.Write(); err != nil {
+			fmt.Printf("An error occurred when writing %s: %v\n", target, err)
+			continue
+		}
+		os.Remove(v)
+
+		if argVerbose {
+			fmt.Printf("Restored %d element(s) to %s\n", restored, target)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// watchInterval is how often watched files are polled for changes
+const watchInterval = 500 * time.Millisecond
+
+// selectInterval is how often the watched files are selected again, to find new files
+const selectInterval = 10 * time.Second
+
+// fileState holds the modification time and size of a file
+type fileState struct {
+	modTime int64
+	size    int64
+}
+
+// statFile returns the state of the file at path, or an empty state if it doesn't exist
+func statFile(path string) fileState {
+	f, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: f.ModTime().UnixNano(), size: f.Size()}
+}
+
+// watch polls files and calls fn for every file that was saved. A file is passed to
+// fn once it didn't change for one interval, so files aren't processed while they
+// are being written. The files are replaced by the result of selected every
+// selectInterval, so new files are watched too. Changes made by fn are ignored.
+// watch never returns.
+func watch(files []string, selected func() []string, fn func(path string)) {
+
+	known := make(map[string]fileState)
+	for _, path := range files {
+		known[path] = statFile(path)
+	}
+
+	pending := make(map[string]fileState)
+	lastSelected := time.Now()
+	for {
+		time.Sleep(watchInterval)
+
+		if time.Since(lastSelected) >= selectInterval {
+			files = selected()
+			lastSelected = time.Now()
+		}
+
+		for _, path := range files {
+			state := statFile(path)
+			if state == known[path] {
+				delete(pending, path)
+				continue
+			}
+
+			// Wait until the file didn't change for one interval
+			if previous, ok := pending[path]; !ok || previous != state {
+				pending[path] = state
+				continue
+			}
+
+			delete(pending, path)
+			fn(path)
+			known[path] = statFile(path)
+		}
+	}
+}
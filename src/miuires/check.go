@@ -0,0 +1,208 @@
+package miuires
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Checker checks resources, optionally against their untranslated source src
+type Checker func(res *Resources, src *Resources) []Issue
+
+// CheckNames holds the names of all available checks in the order they are run
+var CheckNames = []string{
+	CheckNameArrays,
+	CheckNameIntegerArrays,
+	CheckNameMarkup,
+	CheckNameXliff,
+}
+
+// Checks holds all available checks by name
+var Checks = map[string]Checker{
+	CheckNameArrays:        (*Resources).CheckArrays,
+	CheckNameIntegerArrays: (*Resources).CheckIntegerArrays,
+	CheckNameMarkup:        (*Resources).CheckMarkup,
+	CheckNameXliff:         (*Resources).CheckXliff,
+}
+
+// integerPattern matches decimal and hexadecimal integer literals
+var integerPattern = regexp.MustCompile(`^[-+]?(0[xX][0-9a-fA-F]+|[0-9]+)$`)
+
+// Issue describes a problem found when checking resources
+type Issue struct {
+	FilePath string
+	Key      string
+	Message  string
+}
+
+// String returns the issue formatted as a single line
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.FilePath, i.Key, i.Message)
+}
+
+// arraySiblingSuffixes holds the suffixes of arrays that belong together, like
+// the entries and values of a list preference. Longer suffixes come first.
+var arraySiblingSuffixes = []string{
+	"_entry_values",
+	"_entryvalues",
+	"_summaries",
+	"_entries",
+	"_values",
+	"_titles",
+	"_labels",
+	"_names",
+}
+
+// SourcePath returns the path of the untranslated source of the resources in srcDir.
+// MIUI source resources are stored as <app>/res/values/<file type>.
+func (res *Resources) SourcePath(srcDir string) string {
+	return SourcePath(res.FilePath, srcDir)
+}
+
+// SourcePath returns the path of the untranslated source in srcDir of the resources
+// at filePath, without loading them
+func SourcePath(filePath string, srcDir string) string {
+	return filepath.Join(srcDir, getAppName(filePath), "res", "values", getFileType(filePath))
+}
+
+// CheckArrays compares the item count of each array with the same array in the source
+// resources, and with sibling arrays that share the same stem. Translated arrays
+// that lose or gain items shift indices against their untranslated siblings.
+// src may be nil, in which case only siblings within res are compared.
+func (res *Resources) CheckArrays(src *Resources) (issues []Issue) {
+
+	if res.FileType != FileTypeArrays {
+		return nil
+	}
+
+	// Group the sorted keys by stem once, so siblings are found without a scan
+	// over all keys for each array
+	siblings := arraysByStem(res)
+	srcSiblings := arraysByStem(src)
+
+	for _, key := range res.sortedElementKeys() {
+		items := res.Elements[key].GetItems()
+
+		// Compare with the same array in the source
+		if src != nil {
+			if srcElement, ok := src.Elements[key]; ok && len(srcElement.GetItems()) != len(items) {
+				issues = append(issues, Issue{
+					FilePath: res.FilePath,
+					Key:      key,
+					Message:  fmt.Sprintf("array has %d items, source has %d", len(items), len(srcElement.GetItems())),
+				})
+			}
+		}
+
+		stem := arrayStem(key)
+		if stem == "" {
+			continue
+		}
+
+		// Compare with translated siblings, each pair is reported once
+		for _, sibling := range siblings[stem] {
+			if sibling <= key {
+				continue
+			}
+			if count := len(res.Elements[sibling].GetItems()); count != len(items) {
+				issues = append(issues, Issue{
+					FilePath: res.FilePath,
+					Key:      key,
+					Message:  fmt.Sprintf("array has %d items, sibling %s has %d", len(items), sibling, count),
+				})
+			}
+		}
+
+		// Compare with untranslated siblings that only exist in the source
+		for _, sibling := range srcSiblings[stem] {
+			if sibling == key {
+				continue
+			}
+			if _, ok := res.Elements[sibling]; ok {
+				continue
+			}
+			if count := len(src.Elements[sibling].GetItems()); count != len(items) {
+				issues = append(issues, Issue{
+					FilePath: res.FilePath,
+					Key:      key,
+					Message:  fmt.Sprintf("array has %d items, source sibling %s has %d", len(items), sibling, count),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// CheckIntegerArrays verifies that every item of an integer-array is an integer literal
+// or a reference to an integer resource. src is not used.
+func (res *Resources) CheckIntegerArrays(src *Resources) (issues []Issue) {
+
+	if res.FileType != FileTypeArrays {
+		return nil
+	}
+
+	for _, key := range res.sortedElementKeys() {
+		ea, ok := res.Elements[key].(*ElementArrays)
+		if !ok || ea.form != "integer-array" {
+			continue
+		}
+		for index, item := range ea.items {
+			if !isIntegerItem(item) {
+				issues = append(issues, Issue{
+					FilePath: res.FilePath,
+					Key:      key,
+					Message:  fmt.Sprintf("item %d of integer-array is not an integer: %q", index, item),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// isIntegerItem returns true if item is an integer literal or integer resource reference
+func isIntegerItem(item string) bool {
+	item = trimSpace(item)
+	if strings.HasPrefix(item, "@integer/") || strings.HasPrefix(item, "@android:integer/") {
+		return true
+	}
+	return integerPattern.MatchString(item)
+}
+
+// arrayStem returns the name of an array without its sibling suffix. An empty
+// string is returned if the array has no known sibling suffix.
+func arrayStem(name string) string {
+	for _, suffix := range arraySiblingSuffixes {
+		if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return ""
+}
+
+// arraysByStem returns the sorted keys of the arrays in res that have a sibling
+// suffix, grouped by their stem. res may be nil.
+func arraysByStem(res *Resources) map[string][]string {
+	stems := make(map[string][]string)
+	if res == nil {
+		return stems
+	}
+	for _, key := range res.sortedElementKeys() {
+		if stem := arrayStem(key); stem != "" {
+			stems[stem] = append(stems[stem], key)
+		}
+	}
+	return stems
+}
+
+// sortedElementKeys returns the keys of the elements currently held by res in
+// sorted order
+func (res *Resources) sortedElementKeys() []string {
+	keys := make([]string, 0, len(res.Elements))
+	for k := range res.Elements {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,73 @@
+package miuires
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newTestArrays returns arrays.xml resources with the item counts by key
+func newTestArrays(counts map[string]int) *Resources {
+	res := &Resources{
+		FilePath: "Settings.apk/res/values-nl/arrays.xml",
+		FileType: FileTypeArrays,
+		AppName:  "Settings.apk",
+		Elements: make(map[string]Elementer),
+	}
+	for key, count := range counts {
+		res.Elements[key] = &ElementArrays{name: key, form: "string-array", items: make([]string, count)}
+	}
+	res.Keys = res.sortedElementKeys()
+	return res
+}
+
+func TestCheckArrays(t *testing.T) {
+
+	tests := []struct {
+		name string
+		res  map[string]int
+		src  map[string]int
+		want []string
+	}{
+		{
+			name: "consistent",
+			res:  map[string]int{"mode_entries": 2, "mode_values": 2},
+			src:  map[string]int{"mode_entries": 2, "mode_values": 2},
+		},
+		{
+			name: "source count",
+			res:  map[string]int{"colors": 2},
+			src:  map[string]int{"colors": 3},
+			want: []string{"colors: array has 2 items, source has 3"},
+		},
+		{
+			name: "translated siblings",
+			res:  map[string]int{"mode_entries": 2, "mode_summaries": 3, "other_entries": 4},
+			want: []string{"mode_entries: array has 2 items, sibling mode_summaries has 3"},
+		},
+		{
+			name: "source sibling",
+			res:  map[string]int{"mode_entries": 2},
+			src:  map[string]int{"mode_entries": 2, "mode_values": 3},
+			want: []string{"mode_entries: array has 2 items, source sibling mode_values has 3"},
+		},
+		{
+			name: "no stem",
+			res:  map[string]int{"entries": 2, "values": 3},
+		},
+	}
+
+	for _, tt := range tests {
+		var src *Resources
+		if tt.src != nil {
+			src = newTestArrays(tt.src)
+		}
+
+		var got []string
+		for _, issue := range newTestArrays(tt.res).CheckArrays(src) {
+			got = append(got, issue.Key+": "+issue.Message)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -9,6 +9,44 @@ const FilterModeSuffix = "suffix"
 // FilterModeContains represents the contains filter mode
 const FilterModeContains = "contains"
 
+// FilterModeExact represents the exact match filter mode
+const FilterModeExact = "exact"
+
+// FilterModeGlob represents the glob pattern filter mode
+const FilterModeGlob = "glob"
+
+// FilterModeRegex represents the regular expression filter mode
+const FilterModeRegex = "regex"
+
+// FilterModeCompound is the mode reported for removals by compound rules
+const FilterModeCompound = "compound"
+
+// IdenticalRemove represents removing translations that are identical to the source
+const IdenticalRemove = "remove"
+
+// IdenticalReport represents reporting translations that are identical to the source
+const IdenticalReport = "report"
+
+// FilterOnKey represents a compound rule condition on the key of an element
+const FilterOnKey = "key"
+
+// FilterOnValue represents a compound rule condition on the value of a strings element
+const FilterOnValue = "value"
+
+// FilterOnItems represents a compound rule condition on the items of an arrays or
+// plurals element
+const FilterOnItems = "items"
+
+// FilterOnAttribute is the prefix of a compound rule condition on an attribute of
+// an element, for example attribute:translatable
+const FilterOnAttribute = "attribute:"
+
+// FilterItemsAny represents matching an element if any of its items matches
+const FilterItemsAny = "any"
+
+// FilterItemsAll represents matching an element only if all of its items match
+const FilterItemsAll = "all"
+
 // FileTypeArrays represents arrays.xml
 const FileTypeArrays = "arrays.xml"
 
@@ -17,3 +55,30 @@ const FileTypePlurals = "plurals.xml"
 
 // FileTypeStrings represents strings.xml
 const FileTypeStrings = "strings.xml"
+
+// CheckNameArrays is the name of the array item count check
+const CheckNameArrays = "arrays"
+
+// CheckNameIntegerArrays is the name of the integer-array contents check
+const CheckNameIntegerArrays = "integer-arrays"
+
+// CheckNameMarkup is the name of the inline markup check
+const CheckNameMarkup = "markup"
+
+// CheckNameXliff is the name of the xliff:g placeholder check
+const CheckNameXliff = "xliff"
+
+// SortKey represents writing elements ordered by key
+const SortKey = "key"
+
+// SortFile represents writing elements in the order of the file they were loaded from
+const SortFile = "file"
+
+// StyleSpaces represents indenting with four spaces
+const StyleSpaces = "spaces"
+
+// StyleCompact represents indenting with two spaces
+const StyleCompact = "compact"
+
+// StyleTabs represents indenting with tabs
+const StyleTabs = "tabs"
@@ -6,20 +6,30 @@ import (
 	"strings"
 )
 
+// pluralQuantities holds the quantities supported by Android plurals in their
+// canonical order
+var pluralQuantities = []string{"zero", "one", "two", "few", "many", "other"}
+
 // Elementer is an interface that holds common behavior for MIUI resources
 type Elementer interface {
 	GetName() (name string)
 	GetItems() (items []string)
 	GetValue() (value string)
+	GetAttribute(name string) (value string, ok bool)
 	Write() []byte
+	WriteIndent(indent string) []byte
 }
 
+// defaultIndent is the indentation used by Write
+const defaultIndent = "    "
+
 // ElementArrays implements the Elementer interface, and holds information and behavior
 // to handle MIUI arrays.xml
 type ElementArrays struct {
-	name  string
-	form  string
-	items []string
+	name       string
+	form       string
+	items      []string
+	attributes map[string]string
 }
 
 // NewArrays parses a string and converts it into an arrays element if possible
@@ -67,6 +77,7 @@ func NewArrays(base string) (bool, *ElementArrays) {
 
 			// extract name
 			ea.name = getElementParameter(str, "name")
+			ea.attributes = getElementAttributes(str)
 
 			// If array is empty we break out, otherwise we continue
 			if strings.Contains(str, "/>") {
@@ -116,22 +127,33 @@ func (ea *ElementArrays) GetValue() (value string) {
 	return ""
 }
 
+// GetAttribute returns the value of an attribute of the arrays element
+func (ea *ElementArrays) GetAttribute(name string) (value string, ok bool) {
+	value, ok = ea.attributes[name]
+	return
+}
+
 // Write writes the contents of the arrays element to a slice of bytes
 func (ea *ElementArrays) Write() []byte {
+	return ea.WriteIndent(defaultIndent)
+}
+
+// WriteIndent writes the element like Write, using indent for each level of indentation
+func (ea *ElementArrays) WriteIndent(indent string) []byte {
 
 	// Handle empty array
 	if len(ea.items) == 0 {
-		return []byte(fmt.Sprintf(`    <%s name="%s"/>`+"\n", ea.form, ea.name))
+		return []byte(fmt.Sprintf(`%s<%s name="%s"/>`+"\n", indent, ea.form, ea.name))
 	}
 
 	// Handle normal arrays
 	w := bytes.NewBuffer([]byte{})
 	buf := bytes.NewBufferString("")
-	buf.WriteString(fmt.Sprintf(`    <%s name="%s">`+"\n", ea.form, ea.name))
+	buf.WriteString(fmt.Sprintf(`%s<%s name="%s">`+"\n", indent, ea.form, ea.name))
 	for _, item := range ea.items {
-		buf.WriteString(fmt.Sprintf(`        <item>%s</item>`+"\n", item))
+		buf.WriteString(fmt.Sprintf(`%s%s<item>%s</item>`+"\n", indent, indent, item))
 	}
-	buf.WriteString(fmt.Sprintf(`    </%s>`+"\n", ea.form))
+	buf.WriteString(fmt.Sprintf(`%s</%s>`+"\n", indent, ea.form))
 	w.WriteString(buf.String())
 	return w.Bytes()
 }
@@ -142,6 +164,7 @@ type ElementPlurals struct {
 	name       string
 	items      []string
 	quantities []string
+	attributes map[string]string
 }
 
 // NewPlurals parses a string and converts it into an plurals element if possible
@@ -175,6 +198,7 @@ func NewPlurals(base string) (bool, *ElementPlurals) {
 			// Trim prefix and suffix
 			str = trimSpace(str)
 			ep.name = getElementParameter(str, "name")
+			ep.attributes = getElementAttributes(str)
 			continue
 		}
 
@@ -201,6 +225,55 @@ func NewPlurals(base string) (bool, *ElementPlurals) {
 	return true, &ep
 }
 
+// normalize sorts the items of the plurals element in canonical quantity order.
+// When a quantity occurs more than once the last item wins, and the duplicate
+// quantities are returned so they can be reported. An error is returned when
+// an unknown quantity is encountered.
+func (ep *ElementPlurals) normalize() (duplicates []string, err error) {
+
+	values := make(map[string]string)
+	for index, quantity := range ep.quantities {
+		if !isPluralQuantity(quantity) {
+			return nil, fmt.Errorf("plurals %s has unknown quantity %q", ep.name, quantity)
+		}
+		if _, ok := values[quantity]; ok {
+			duplicates = append(duplicates, quantity)
+		}
+		values[quantity] = ep.items[index]
+	}
+
+	// Rebuild items and quantities in canonical order
+	ep.items = ep.items[:0]
+	ep.quantities = ep.quantities[:0]
+	for _, quantity := range pluralQuantities {
+		if value, ok := values[quantity]; ok {
+			ep.items = append(ep.items, value)
+			ep.quantities = append(ep.quantities, quantity)
+		}
+	}
+	return duplicates, nil
+}
+
+// quantity returns the item of the plurals element for quantity
+func (ep *ElementPlurals) quantity(quantity string) (item string, ok bool) {
+	for index, q := range ep.quantities {
+		if q == quantity {
+			return ep.items[index], true
+		}
+	}
+	return "", false
+}
+
+// isPluralQuantity returns true if quantity is a known plurals quantity
+func isPluralQuantity(quantity string) bool {
+	for _, q := range pluralQuantities {
+		if q == quantity {
+			return true
+		}
+	}
+	return false
+}
+
 // GetName returns the name (key) of the plurals element
 func (ep *ElementPlurals) GetName() (name string) {
 	return ep.name
@@ -216,15 +289,26 @@ func (ep *ElementPlurals) GetValue() (value string) {
 	return ""
 }
 
+// GetAttribute returns the value of an attribute of the plurals element
+func (ep *ElementPlurals) GetAttribute(name string) (value string, ok bool) {
+	value, ok = ep.attributes[name]
+	return
+}
+
 // Write writes the contents of the plurals element to a slice of bytes
 func (ep *ElementPlurals) Write() []byte {
+	return ep.WriteIndent(defaultIndent)
+}
+
+// WriteIndent writes the element like Write, using indent for each level of indentation
+func (ep *ElementPlurals) WriteIndent(indent string) []byte {
 	w := bytes.NewBuffer([]byte{})
 	buf := bytes.NewBufferString("")
-	buf.WriteString(fmt.Sprintf(`    <plurals name="%s">`+"\n", ep.name))
+	buf.WriteString(fmt.Sprintf(`%s<plurals name="%s">`+"\n", indent, ep.name))
 	for index, item := range ep.items {
-		buf.WriteString(fmt.Sprintf(`        <item quantity="%s">%s</item>`+"\n", ep.quantities[index], item))
+		buf.WriteString(fmt.Sprintf(`%s%s<item quantity="%s">%s</item>`+"\n", indent, indent, ep.quantities[index], item))
 	}
-	buf.WriteString(fmt.Sprintf(`    </plurals>` + "\n"))
+	buf.WriteString(fmt.Sprintf(`%s</plurals>`+"\n", indent))
 	w.WriteString(buf.String())
 	return w.Bytes()
 }
@@ -232,9 +316,10 @@ func (ep *ElementPlurals) Write() []byte {
 // ElementStrings implements the Elementer interface, and holds information and behavior
 // to handle MIUI strings.xml
 type ElementStrings struct {
-	name      string
-	value     string
-	formatted bool
+	name       string
+	value      string
+	formatted  bool
+	attributes map[string]string
 }
 
 // NewStrings parses a string and converts it into a strings element if possible
@@ -259,6 +344,9 @@ func NewStrings(base string) (bool, *ElementStrings) {
 	// Trim prefix
 	base = strings.TrimPrefix(base, "<string ")
 
+	// Get the attributes
+	es.attributes = getElementAttributes(base)
+
 	// Handle empty strings
 	if strings.Contains(base, `"/>`) || strings.Contains(base, `" />`) {
 		baseSlice := strings.Split(base, `name="`)
@@ -295,12 +383,23 @@ func (es *ElementStrings) GetValue() (value string) {
 	return es.value
 }
 
+// GetAttribute returns the value of an attribute of the strings element
+func (es *ElementStrings) GetAttribute(name string) (value string, ok bool) {
+	value, ok = es.attributes[name]
+	return
+}
+
 // Write writes the contents of the element strings to a slice of bytes
 func (es *ElementStrings) Write() []byte {
+	return es.WriteIndent(defaultIndent)
+}
+
+// WriteIndent writes the element like Write, using indent for each level of indentation
+func (es *ElementStrings) WriteIndent(indent string) []byte {
 
 	// Handle empty strings
 	if es.value == "" {
-		return []byte(fmt.Sprintf(`    <string name="%s"/>`, es.name) + "\n")
+		return []byte(fmt.Sprintf(`%s<string name="%s"/>`, indent, es.name) + "\n")
 	}
 
 	// Handle normal strings
@@ -308,5 +407,5 @@ func (es *ElementStrings) Write() []byte {
 	if es.formatted {
 		formatString = ` formatted="false"`
 	}
-	return []byte(fmt.Sprintf(`    <string name="%s"%s>%s</string>`+"\n", es.name, formatString, es.GetValue()))
+	return []byte(fmt.Sprintf(`%s<string name="%s"%s>%s</string>`+"\n", indent, es.name, formatString, es.GetValue()))
 }
@@ -0,0 +1,90 @@
+package miuires
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPluralsNormalize(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		quantities     []string
+		items          []string
+		wantQuantities []string
+		wantItems      []string
+		wantDuplicates []string
+		wantErr        bool
+	}{
+		{
+			name:           "canonical order",
+			quantities:     []string{"one", "other"},
+			items:          []string{"1 file", "%d files"},
+			wantQuantities: []string{"one", "other"},
+			wantItems:      []string{"1 file", "%d files"},
+		},
+		{
+			name:           "sorted",
+			quantities:     []string{"other", "few", "zero", "one"},
+			items:          []string{"o", "f", "z", "1"},
+			wantQuantities: []string{"zero", "one", "few", "other"},
+			wantItems:      []string{"z", "1", "f", "o"},
+		},
+		{
+			name:           "last duplicate wins",
+			quantities:     []string{"one", "other", "one"},
+			items:          []string{"first", "o", "last"},
+			wantQuantities: []string{"one", "other"},
+			wantItems:      []string{"last", "o"},
+			wantDuplicates: []string{"one"},
+		},
+		{
+			name:       "unknown quantity",
+			quantities: []string{"one", "several"},
+			items:      []string{"1", "n"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		ep := &ElementPlurals{name: "files", quantities: tt.quantities, items: tt.items}
+		duplicates, err := ep.normalize()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(ep.quantities, tt.wantQuantities) || !reflect.DeepEqual(ep.items, tt.wantItems) {
+			t.Errorf("%s: got %v %v, want %v %v", tt.name, ep.quantities, ep.items, tt.wantQuantities, tt.wantItems)
+		}
+		if !reflect.DeepEqual(duplicates, tt.wantDuplicates) {
+			t.Errorf("%s: got duplicates %v, want %v", tt.name, duplicates, tt.wantDuplicates)
+		}
+	}
+}
+
+func TestIsPluralQuantity(t *testing.T) {
+
+	tests := []struct {
+		quantity string
+		want     bool
+	}{
+		{"zero", true},
+		{"one", true},
+		{"two", true},
+		{"few", true},
+		{"many", true},
+		{"other", true},
+		{"One", false},
+		{"several", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPluralQuantity(tt.quantity); got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.quantity, got, tt.want)
+		}
+	}
+}
@@ -1,28 +1,138 @@
 package miuires
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
-	yaml "gopkg.in/yaml.v2"
+	yaml "gopkg.in/yaml.v3"
 )
 
 // FilterConfig holds filter rules
 type FilterConfig struct {
+	// Include holds paths of filter configurations that are merged in order before
+	// the rules of this configuration. Relative paths are relative to this file.
+	Include []string `yaml:"include"`
+
 	StringsKeyRules   map[string][]FilterRules `yaml:"strings_key_rules"`
 	ArraysKeyRules    map[string][]FilterRules `yaml:"arrays_key_rules"`
 	PluralsKeyRules   map[string][]FilterRules `yaml:"plurals_key_rules"`
 	StringsValueRules map[string][]FilterRules `yaml:"strings_value_rules"`
 	ArraysValueRules  map[string][]FilterRules `yaml:"arrays_value_rules"`
+	PluralsValueRules map[string][]FilterRules `yaml:"plurals_value_rules"`
+
+	// Keep rules match keys of elements that are never removed, even if they are
+	// matched by one of the rules above
+	StringsKeepRules map[string][]FilterRules `yaml:"strings_keep_rules"`
+	ArraysKeepRules  map[string][]FilterRules `yaml:"arrays_keep_rules"`
+	PluralsKeepRules map[string][]FilterRules `yaml:"plurals_keep_rules"`
+
+	// Compound rules combine conditions on the key, value, items and attributes of
+	// an element
+	StringsRules map[string][]FilterRules `yaml:"strings_rules"`
+	ArraysRules  map[string][]FilterRules `yaml:"arrays_rules"`
+	PluralsRules map[string][]FilterRules `yaml:"plurals_rules"`
+
+	// RemoveIntegerArrays removes all integer-array elements, these are never
+	// translatable. It is nil if it isn't set, so a configuration that includes another
+	// configuration can turn it off explicitly.
+	RemoveIntegerArrays *bool `yaml:"remove_integer_arrays"`
+
+	// Identical removes or reports translations that are identical to the source,
+	// unless they are matched by one of the compound IdenticalAllowRules. It is nil if
+	// it isn't set, an empty string clears an included setting.
+	Identical           *string                  `yaml:"identical"`
+	IdenticalAllowRules map[string][]FilterRules `yaml:"identical_allow_rules"`
+
+	// Locales holds rules that only apply to resources of a locale, like nl or zh-rCN.
+	// Rules of a language, like zh, apply to all regions of that language.
+	Locales map[string]*FilterConfig `yaml:"locales"`
+
+	// locale holds the locale of a configuration in Locales
+	locale string
+
+	// appPatterns holds the compiled application names that are regular expressions
+	appPatterns map[string]*regexp.Regexp
 }
 
-// FilterRules holds rules used to filter keys and/or values
+// FilterRules holds rules used to filter keys and/or values. In compound rules a rule
+// is either a condition on the part of the element selected by On, or a combination
+// of the All, Any and Not rules that must all be met.
 type FilterRules struct {
 	Match string `yaml:"match"`
 	Mode  string `yaml:"mode"`
+
+	// Remove removes an equal rule inherited from an included configuration, instead
+	// of adding the rule
+	Remove bool `yaml:"remove"`
+
+	// Items determines whether any (default) or all items of an arrays or plurals
+	// element must match
+	Items string `yaml:"items"`
+
+	On  string        `yaml:"on"`
+	All []FilterRules `yaml:"all"`
+	Any []FilterRules `yaml:"any"`
+	Not *FilterRules  `yaml:"not"`
+
+	// regexp holds the compiled Match of rules in glob and regex mode
+	regexp *regexp.Regexp
+
+	// line holds the line of the rule in the YAML file
+	line int
 }
 
-// GetFilterConfigFromFile returns a new FilterConfig from a YAML file
+// IdenticalMode returns IdenticalRemove or IdenticalReport if translations identical
+// to the source are filtered, or an empty string if they are not
+func (fc *FilterConfig) IdenticalMode() string {
+	if fc.Identical == nil {
+		return ""
+	}
+	return *fc.Identical
+}
+
+// IdenticalModeFor returns the identical setting that applies to resources of locale.
+// The setting of a locale overrides the setting of its language, which overrides
+// the general setting.
+func (fc *FilterConfig) IdenticalModeFor(locale string) string {
+	var mode string
+	for _, c := range fc.scoped(locale) {
+		if c.Identical != nil {
+			mode = *c.Identical
+		}
+	}
+	return mode
+}
+
+// FiltersIdentical returns true if translations identical to the source are filtered
+// for any locale
+func (fc *FilterConfig) FiltersIdentical() bool {
+	if fc.IdenticalMode() != "" {
+		return true
+	}
+	for _, lc := range fc.Locales {
+		if lc != nil && lc.IdenticalMode() != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// removesIntegerArrays returns true if all integer-array elements are removed
+func (fc *FilterConfig) removesIntegerArrays() bool {
+	return fc.RemoveIntegerArrays != nil && *fc.RemoveIntegerArrays
+}
+
+// GetFilterConfigFromFile returns a new FilterConfig from a YAML file, merged with the
+// configurations it includes. Unknown fields, unknown modes, invalid patterns and
+// invalid application names are returned as errors with line numbers.
 func GetFilterConfigFromFile(r *os.File) (*FilterConfig, error) {
 
 	// Read data from reader
@@ -31,13 +141,500 @@ func GetFilterConfigFromFile(r *os.File) (*FilterConfig, error) {
 		return nil, err
 	}
 
+	path, err := filepath.Abs(r.Name())
+	if err != nil {
+		return nil, err
+	}
+	return loadFilterConfig(path, data, nil)
+}
+
+// GetFilterConfigFromFiles returns the filter configurations in the files at paths,
+// each merged with the configurations it includes, merged in order. Problems are
+// prefixed with the path of the file they were found in.
+func GetFilterConfigFromFiles(paths []string) (*FilterConfig, error) {
+
+	merged := &FilterConfig{}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadFile(abs)
+		if err != nil {
+			return nil, err
+		}
+
+		fc, err := loadFilterConfig(abs, data, nil)
+		if err != nil {
+			return nil, inFile(path, err)
+		}
+		if err := merged.merge(fc); err != nil {
+			return nil, inFile(path, err)
+		}
+	}
+
+	if err := merged.compile(nil); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// decodeFilterConfig returns a new validated FilterConfig from YAML data
+func decodeFilterConfig(data []byte) (*FilterConfig, error) {
+
 	// Initialise FilterConfig
 	var fc FilterConfig
 
-	// Unmarshall data in HCL to config
-	if err := yaml.Unmarshal(data, &fc); err != nil {
+	// Unmarshal data in YAML to config, unknown fields are not allowed
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&fc); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	// The document node is used to find the lines of application names
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	var document *yaml.Node
+	if len(root.Content) > 0 {
+		document = root.Content[0]
+	}
+
+	// Validate and compile rules
+	if err := fc.compile(document); err != nil {
 		return nil, err
 	}
 
 	return &fc, nil
 }
+
+// filterSections holds the names of all sections of rules in a FilterConfig
+var filterSections = []string{
+	"strings_key_rules",
+	"arrays_key_rules",
+	"plurals_key_rules",
+	"strings_value_rules",
+	"arrays_value_rules",
+	"plurals_value_rules",
+	"strings_keep_rules",
+	"arrays_keep_rules",
+	"plurals_keep_rules",
+	"strings_rules",
+	"arrays_rules",
+	"plurals_rules",
+	"identical_allow_rules",
+}
+
+// section returns a pointer to the section of rules by its name
+func (fc *FilterConfig) section(name string) *map[string][]FilterRules {
+	switch name {
+	case "strings_key_rules":
+		return &fc.StringsKeyRules
+	case "arrays_key_rules":
+		return &fc.ArraysKeyRules
+	case "plurals_key_rules":
+		return &fc.PluralsKeyRules
+	case "strings_value_rules":
+		return &fc.StringsValueRules
+	case "arrays_value_rules":
+		return &fc.ArraysValueRules
+	case "plurals_value_rules":
+		return &fc.PluralsValueRules
+	case "strings_keep_rules":
+		return &fc.StringsKeepRules
+	case "arrays_keep_rules":
+		return &fc.ArraysKeepRules
+	case "plurals_keep_rules":
+		return &fc.PluralsKeepRules
+	case "strings_rules":
+		return &fc.StringsRules
+	case "arrays_rules":
+		return &fc.ArraysRules
+	case "plurals_rules":
+		return &fc.PluralsRules
+	case "identical_allow_rules":
+		return &fc.IdenticalAllowRules
+	}
+	return nil
+}
+
+// compile validates and compiles the patterns of all rules, so they are compiled only
+// once. node is the YAML mapping node of the configuration, which is used to report
+// the lines of application names. It may be nil.
+func (fc *FilterConfig) compile(node *yaml.Node) error {
+	var problems ConfigErrors
+
+	fc.appPatterns = make(map[string]*regexp.Regexp)
+	for _, sectionName := range filterSections {
+		section := *fc.section(sectionName)
+		compound := isCompoundSection(sectionName)
+		for _, appName := range sortedKeys(section) {
+			if err := fc.compileAppName(appName); err != nil {
+				problems = append(problems, fmt.Sprintf("line %d: %s: %v", keyLine(node, sectionName, appName), sectionName, err))
+			}
+			rules := section[appName]
+			for i := range rules {
+				for _, problem := range rules[i].compile(compound) {
+					problems = append(problems, withContext(sectionName+": "+appName, problem))
+				}
+			}
+		}
+	}
+
+	if identical := fc.IdenticalMode(); identical != "" && identical != IdenticalRemove && identical != IdenticalReport {
+		problems = append(problems, fmt.Sprintf("line %d: unknown identical %q, use remove or report", keyLine(node, "identical"), identical))
+	}
+
+	for _, locale := range sortedLocales(fc.Locales) {
+		lc := fc.Locales[locale]
+		if lc == nil {
+			continue
+		}
+		if len(lc.Locales) > 0 {
+			problems = append(problems, fmt.Sprintf("line %d: locales: %s: locales can't be nested", keyLine(node, "locales", locale), locale))
+		}
+		if len(lc.Include) > 0 {
+			problems = append(problems, fmt.Sprintf("line %d: locales: %s: include is only allowed at the top level", keyLine(node, "locales", locale, "include"), locale))
+		}
+		lc.locale = locale
+		if err := lc.compile(valueNode(node, "locales", locale)); err != nil {
+			for _, problem := range err.(ConfigErrors) {
+				problems = append(problems, withContext("locales: "+locale, problem))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// scoped returns the filter configurations that apply to resources of locale: the
+// general configuration, followed by the configurations of the matching language
+// and locale
+func (fc *FilterConfig) scoped(locale string) []*FilterConfig {
+	configs := []*FilterConfig{fc}
+	if locale == "" {
+		return configs
+	}
+
+	language := strings.Split(locale, "-")[0]
+	if lc := fc.Locales[language]; lc != nil {
+		configs = append(configs, lc)
+	}
+	if lc := fc.Locales[locale]; lc != nil && locale != language {
+		configs = append(configs, lc)
+	}
+	return configs
+}
+
+// compileAppName compiles an application name that is a regular expression, and
+// validates application names that are glob patterns
+func (fc *FilterConfig) compileAppName(appName string) error {
+	if isAppRegex(appName) {
+		if _, ok := fc.appPatterns[appName]; ok {
+			return nil
+		}
+		re, err := regexp.Compile(appName[1 : len(appName)-1])
+		if err != nil {
+			return fmt.Errorf("invalid application regex %q: %v", appName, err)
+		}
+		fc.appPatterns[appName] = re
+		return nil
+	}
+	if _, err := path.Match(appName, ""); err != nil {
+		return fmt.Errorf("invalid application glob %q: %v", appName, err)
+	}
+	if appName != "all" && appName != "framework-res" && !strings.ContainsAny(appName, "*?[") &&
+		!strings.HasSuffix(appName, ".apk") && !strings.HasSuffix(appName, ".jar") {
+		return fmt.Errorf("application %q is not all, an .apk or .jar name or a pattern", appName)
+	}
+	return nil
+}
+
+// isAppRegex returns true if the application name is a regular expression, which
+// is enclosed in slashes like /^Miui.*\.apk$/
+func isAppRegex(appName string) bool {
+	return len(appName) > 2 && strings.HasPrefix(appName, "/") && strings.HasSuffix(appName, "/")
+}
+
+// appRule holds a rule together with the application name it was defined for
+type appRule struct {
+	FilterRules
+	app string
+}
+
+// rules returns the rules of section that apply to the application. General rules
+// from "all" come first, followed by the rules of application names that are glob
+// patterns or regular expressions in sorted order, followed by the rules of the
+// exact application name.
+func (fc *FilterConfig) rules(section map[string][]FilterRules, appName string) (rules []appRule) {
+
+	names := []string{"all"}
+	var patterns []string
+	for name := range section {
+		if name != "all" && name != appName && fc.appMatches(name, appName) {
+			patterns = append(patterns, name)
+		}
+	}
+	sort.Strings(patterns)
+	names = append(names, patterns...)
+	if appName != "all" {
+		names = append(names, appName)
+	}
+
+	for _, name := range names {
+		for _, rule := range section[name] {
+			rules = append(rules, appRule{FilterRules: rule, app: name})
+		}
+	}
+	return rules
+}
+
+// appMatches returns true if the application name pattern matches appName
+func (fc *FilterConfig) appMatches(pattern string, appName string) bool {
+	if isAppRegex(pattern) {
+		re, ok := fc.appPatterns[pattern]
+		return ok && re.MatchString(appName)
+	}
+	ok, _ := path.Match(pattern, appName)
+	return ok
+}
+
+// compile validates the rule and compiles its pattern for the regex and glob modes,
+// including the patterns of compound rules. compound is true if the rule is part of
+// a section of compound rules. It returns the problems found.
+func (fr *FilterRules) compile(compound bool) (problems []string) {
+	line := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf("line %d: ", fr.line)+fmt.Sprintf(format, a...))
+	}
+
+	if fr.isCompound() {
+		if !compound {
+			line("all, any and not are only allowed in strings_rules, arrays_rules and plurals_rules")
+		}
+		if fr.Mode != "" || fr.Match != "" || fr.On != "" {
+			line("a rule can't combine all, any or not with on, match or mode")
+		}
+		for i := range fr.All {
+			problems = append(problems, fr.All[i].compile(compound)...)
+		}
+		for i := range fr.Any {
+			problems = append(problems, fr.Any[i].compile(compound)...)
+		}
+		if fr.Not != nil {
+			problems = append(problems, fr.Not.compile(compound)...)
+		}
+		return problems
+	}
+
+	switch {
+	case compound && fr.On == "":
+		line("on is required in compound rules, use key, value, items or attribute:<name>")
+	case !compound && fr.On != "":
+		line("on is only allowed in strings_rules, arrays_rules and plurals_rules")
+	case fr.On != "" && !isFilterOn(fr.On):
+		line("unknown on %q, use key, value, items or attribute:<name>", fr.On)
+	}
+
+	if fr.Items != "" && fr.Items != FilterItemsAny && fr.Items != FilterItemsAll {
+		line("unknown items %q, use any or all", fr.Items)
+	}
+
+	switch fr.Mode {
+	case FilterModePrefix, FilterModeSuffix, FilterModeContains, FilterModeExact:
+	case FilterModeRegex:
+		var err error
+		if fr.regexp, err = regexp.Compile(fr.Match); err != nil {
+			line("invalid regex %q: %v", fr.Match, err)
+		}
+	case FilterModeGlob:
+		var err error
+		if fr.regexp, err = globRegexp(fr.Match); err != nil {
+			line("invalid glob %q: %v", fr.Match, err)
+		}
+	case "":
+		line("mode is required")
+	default:
+		line("unknown mode %q, use prefix, suffix, contains, exact, glob or regex", fr.Mode)
+	}
+	return problems
+}
+
+// matches returns true if s is matched by the rule
+func (fr *FilterRules) matches(s string) bool {
+	switch fr.Mode {
+	case FilterModeSuffix:
+		return strings.HasSuffix(s, fr.Match)
+	case FilterModePrefix:
+		return strings.HasPrefix(s, fr.Match)
+	case FilterModeContains:
+		return strings.Contains(s, fr.Match)
+	case FilterModeExact:
+		return s == fr.Match
+	case FilterModeGlob, FilterModeRegex:
+		return fr.regexp != nil && fr.regexp.MatchString(s)
+	}
+	return false
+}
+
+// globRegexp returns an anchored regular expression for the glob pattern. Unlike
+// file name patterns, * matches any run of characters including /, and ? matches
+// any single character. Character classes like [a-z] and [!0-9], and escapes with
+// a backslash are supported.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+
+	var buf strings.Builder
+	buf.WriteString(`^(?s:`)
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			buf.WriteString(".*")
+		case '?':
+			buf.WriteString(".")
+		case '\\':
+			if i++; i == len(pattern) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			buf.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			end := i + 1
+			if end < len(pattern) && (pattern[end] == '!' || pattern[end] == '^') {
+				end++
+			}
+			if end < len(pattern) && pattern[end] == ']' {
+				end++
+			}
+			for end < len(pattern) && pattern[end] != ']' {
+				end++
+			}
+			if end == len(pattern) {
+				return nil, fmt.Errorf("missing ]")
+			}
+
+			class := pattern[i+1 : end]
+			buf.WriteByte('[')
+			if class[0] == '!' || class[0] == '^' {
+				buf.WriteByte('^')
+				class = class[1:]
+			}
+			for j := 0; j < len(class); j++ {
+				if class[j] == '\\' || class[j] == '[' || class[j] == ']' || class[j] == '^' {
+					buf.WriteByte('\\')
+				}
+				buf.WriteByte(class[j])
+			}
+			buf.WriteByte(']')
+			i = end
+		default:
+			buf.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	buf.WriteString(`)$`)
+	return regexp.Compile(buf.String())
+}
+
+// matchesItems returns true if any of the items is matched by the rule, or if all
+// items are matched when the rule requires so
+func (fr *FilterRules) matchesItems(items []string) bool {
+	if fr.Items == FilterItemsAll {
+		for _, item := range items {
+			if !fr.matches(item) {
+				return false
+			}
+		}
+		return len(items) > 0
+	}
+
+	for _, item := range items {
+		if fr.matches(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a readable description of the rule
+func (fr *FilterRules) String() string {
+	if !fr.isCompound() {
+		var on string
+		if fr.On != "" {
+			on = fr.On + " "
+		}
+		var items string
+		if fr.Items == FilterItemsAll {
+			items = " (all items)"
+		}
+		return fmt.Sprintf("%s%s %q%s", on, fr.Mode, fr.Match, items)
+	}
+
+	var parts []string
+	if len(fr.All) > 0 {
+		parts = append(parts, "all("+joinRules(fr.All)+")")
+	}
+	if len(fr.Any) > 0 {
+		parts = append(parts, "any("+joinRules(fr.Any)+")")
+	}
+	if fr.Not != nil {
+		parts = append(parts, "not("+fr.Not.String()+")")
+	}
+	return strings.Join(parts, " and ")
+}
+
+// joinRules returns the descriptions of rules separated by commas
+func joinRules(rules []FilterRules) string {
+	descriptions := make([]string, len(rules))
+	for i := range rules {
+		descriptions[i] = rules[i].String()
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// isCompound returns true if the rule combines other rules
+func (fr *FilterRules) isCompound() bool {
+	return len(fr.All) > 0 || len(fr.Any) > 0 || fr.Not != nil
+}
+
+// matchesElement returns true if the element is matched by the compound rule
+func (fr *FilterRules) matchesElement(elementKey string, element Elementer) bool {
+
+	if !fr.isCompound() {
+		switch {
+		case fr.On == FilterOnKey:
+			return fr.matches(elementKey)
+		case fr.On == FilterOnValue:
+			return fr.matches(element.GetValue())
+		case fr.On == FilterOnItems:
+			return fr.matchesItems(element.GetItems())
+		case strings.HasPrefix(fr.On, FilterOnAttribute):
+			value, ok := element.GetAttribute(strings.TrimPrefix(fr.On, FilterOnAttribute))
+			return ok && fr.matches(value)
+		}
+		return false
+	}
+
+	for i := range fr.All {
+		if !fr.All[i].matchesElement(elementKey, element) {
+			return false
+		}
+	}
+	if len(fr.Any) > 0 {
+		var matched bool
+		for i := range fr.Any {
+			if fr.Any[i].matchesElement(elementKey, element) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if fr.Not != nil && fr.Not.matchesElement(elementKey, element) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package miuires
+
+import "testing"
+
+func TestGlobRegexp(t *testing.T) {
+
+	tests := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+		wantErr bool
+	}{
+		{
+			pattern: "*_summary",
+			match:   []string{"_summary", "wifi_summary", "a/b_summary"},
+			noMatch: []string{"wifi_summary_on", "summary"},
+		},
+		{
+			pattern: "title_?",
+			match:   []string{"title_1", "title_a", "title_/"},
+			noMatch: []string{"title_", "title_10"},
+		},
+		{
+			pattern: "item_[0-9]",
+			match:   []string{"item_0", "item_9"},
+			noMatch: []string{"item_a", "item_10"},
+		},
+		{
+			pattern: "item_[!0-9]",
+			match:   []string{"item_a"},
+			noMatch: []string{"item_0"},
+		},
+		{
+			pattern: "item_[^0-9]",
+			match:   []string{"item_a"},
+			noMatch: []string{"item_0"},
+		},
+		{
+			pattern: "[]]",
+			match:   []string{"]"},
+			noMatch: []string{"["},
+		},
+		{
+			pattern: `price_\*`,
+			match:   []string{"price_*"},
+			noMatch: []string{"price_10"},
+		},
+		{
+			pattern: "a.b+c",
+			match:   []string{"a.b+c"},
+			noMatch: []string{"axbbc"},
+		},
+		{
+			pattern: "line*",
+			match:   []string{"line\nbreak"},
+		},
+		{pattern: `trailing\`, wantErr: true},
+		{pattern: "item_[0-9", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		re, err := globRegexp(tt.pattern)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: got error %v, want error %v", tt.pattern, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		for _, s := range tt.match {
+			if !re.MatchString(s) {
+				t.Errorf("%q: %q didn't match", tt.pattern, s)
+			}
+		}
+		for _, s := range tt.noMatch {
+			if re.MatchString(s) {
+				t.Errorf("%q: %q matched", tt.pattern, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package miuires
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// loadFilterConfig returns the FilterConfig in data, read from path, merged with the
+// configurations it includes. stack holds the paths of the configurations that are
+// being loaded, and is used to detect include cycles.
+func loadFilterConfig(path string, data []byte, stack []string) (*FilterConfig, error) {
+
+	fc, err := decodeFilterConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	stack = append(stack, path)
+	merged := &FilterConfig{}
+
+	// Merge included configurations in order
+	for _, include := range fc.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+
+		for _, p := range stack {
+			if p == include {
+				return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), include)
+			}
+		}
+
+		includeData, err := ioutil.ReadFile(include)
+		if err != nil {
+			return nil, fmt.Errorf("include: %v", err)
+		}
+
+		included, err := loadFilterConfig(include, includeData, stack)
+		if err != nil {
+			return nil, inFile(include, err)
+		}
+
+		if err := merged.merge(included); err != nil {
+			return nil, err
+		}
+	}
+
+	// Merge the rules of this configuration last, this also applies rules that remove
+	// inherited rules
+	if err := merged.merge(fc); err != nil {
+		return nil, err
+	}
+	if err := merged.compile(nil); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// inFile prefixes err, or each of its problems, with the path of the file it was
+// found in
+func inFile(path string, err error) error {
+	if problems, ok := err.(ConfigErrors); ok {
+		for i := range problems {
+			problems[i] = path + ": " + problems[i]
+		}
+		return problems
+	}
+	return fmt.Errorf("%s: %v", path, err)
+}
+
+// merge adds the rules of other to the configuration. Rules of other with Remove set
+// remove equal rules from the configuration instead.
+func (fc *FilterConfig) merge(other *FilterConfig) error {
+	var problems ConfigErrors
+
+	for _, sectionName := range filterSections {
+		dst := fc.section(sectionName)
+		for _, appName := range sortedKeys(*other.section(sectionName)) {
+			for _, rule := range (*other.section(sectionName))[appName] {
+				if *dst == nil {
+					*dst = make(map[string][]FilterRules)
+				}
+
+				if !rule.Remove {
+					(*dst)[appName] = append((*dst)[appName], rule)
+					continue
+				}
+
+				rules, removed := removeRule((*dst)[appName], rule)
+				if !removed {
+					problems = append(problems, fmt.Sprintf("line %d: %s: %s: there is no inherited rule %s to remove", rule.line, sectionName, appName, rule.String()))
+				}
+				(*dst)[appName] = rules
+			}
+		}
+	}
+
+	// Settings that are set explicitly override inherited settings
+	if other.RemoveIntegerArrays != nil {
+		fc.RemoveIntegerArrays = other.RemoveIntegerArrays
+	}
+	if other.Identical != nil {
+		fc.Identical = other.Identical
+	}
+
+	for _, locale := range sortedLocales(other.Locales) {
+		if other.Locales[locale] == nil {
+			continue
+		}
+		if fc.Locales == nil {
+			fc.Locales = make(map[string]*FilterConfig)
+		}
+		if fc.Locales[locale] == nil {
+			fc.Locales[locale] = &FilterConfig{}
+		}
+		if err := fc.Locales[locale].merge(other.Locales[locale]); err != nil {
+			for _, problem := range err.(ConfigErrors) {
+				problems = append(problems, withContext("locales: "+locale, problem))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// removeRule returns rules without the rules equal to rule
+func removeRule(rules []FilterRules, rule FilterRules) (kept []FilterRules, removed bool) {
+	for _, r := range rules {
+		if r.String() == rule.String() {
+			removed = true
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept, removed
+}
@@ -0,0 +1,165 @@
+package miuires
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadTestFilterConfig writes files to a temporary directory and loads main.yaml
+func loadTestFilterConfig(t *testing.T, files map[string]string) (*FilterConfig, error) {
+	dir, err := ioutil.TempDir("", "mixml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(dir, "main.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	return GetFilterConfigFromFile(f)
+}
+
+// keyRule returns a strings_key_rules section with an exact rule for every key
+func keyRule(keys ...string) string {
+	section := "strings_key_rules:\n  all:\n"
+	for _, key := range keys {
+		section += "    - match: " + key + "\n      mode: exact\n"
+	}
+	return section
+}
+
+func TestFilterConfigIncludes(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "includes are merged in order before own rules",
+			files: map[string]string{
+				"main.yaml": "include: [a.yaml, b.yaml]\n" + keyRule("c"),
+				"a.yaml":    keyRule("a"),
+				"b.yaml":    keyRule("b"),
+			},
+			want: []string{`exact "a"`, `exact "b"`, `exact "c"`},
+		},
+		{
+			name: "nested includes are merged depth first",
+			files: map[string]string{
+				"main.yaml": "include: [a.yaml]\n" + keyRule("c"),
+				"a.yaml":    "include: [b.yaml]\n" + keyRule("a"),
+				"b.yaml":    keyRule("b"),
+			},
+			want: []string{`exact "b"`, `exact "a"`, `exact "c"`},
+		},
+		{
+			name: "remove removes an equal inherited rule",
+			files: map[string]string{
+				"main.yaml": "include: [a.yaml]\nstrings_key_rules:\n  all:\n    - match: x\n      mode: exact\n      remove: true\n",
+				"a.yaml":    keyRule("x", "y"),
+			},
+			want: []string{`exact "y"`},
+		},
+		{
+			name: "remove without an inherited rule is an error",
+			files: map[string]string{
+				"main.yaml": "include: [a.yaml]\nstrings_key_rules:\n  all:\n    - match: x\n      mode: prefix\n      remove: true\n",
+				"a.yaml":    keyRule("x"),
+			},
+			wantErr: `there is no inherited rule prefix "x" to remove`,
+		},
+		{
+			name: "include cycles are detected",
+			files: map[string]string{
+				"main.yaml": "include: [a.yaml]\n",
+				"a.yaml":    "include: [main.yaml]\n",
+			},
+			wantErr: "include cycle",
+		},
+		{
+			name: "including itself is a cycle",
+			files: map[string]string{
+				"main.yaml": "include: [main.yaml]\n",
+			},
+			wantErr: "include cycle",
+		},
+		{
+			name: "missing includes are an error",
+			files: map[string]string{
+				"main.yaml": "include: [missing.yaml]\n",
+			},
+			wantErr: "include:",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fc, err := loadTestFilterConfig(t, test.files)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got error %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, rule := range fc.StringsKeyRules["all"] {
+				got = append(got, rule.String())
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got rules %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFilterConfigIncludeSettings(t *testing.T) {
+
+	base := "remove_integer_arrays: true\nidentical: report\n"
+
+	tests := []struct {
+		name                 string
+		main                 string
+		wantIntegerArrays    bool
+		wantIdenticalSetting string
+	}{
+		{"unset settings are inherited", "include: [base.yaml]\n", true, IdenticalReport},
+		{"explicit settings override inherited settings", "include: [base.yaml]\nremove_integer_arrays: false\nidentical: remove\n", false, IdenticalRemove},
+		{"an empty identical clears the inherited setting", "include: [base.yaml]\nidentical: \"\"\n", true, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fc, err := loadTestFilterConfig(t, map[string]string{
+				"main.yaml": test.main,
+				"base.yaml": base,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fc.removesIntegerArrays(); got != test.wantIntegerArrays {
+				t.Errorf("got remove_integer_arrays %v, want %v", got, test.wantIntegerArrays)
+			}
+			if got := fc.IdenticalMode(); got != test.wantIdenticalSetting {
+				t.Errorf("got identical %q, want %q", got, test.wantIdenticalSetting)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package miuires
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// markupTags holds the inline tags that are supported in MIUI resource values
+var markupTags = map[string]bool{
+	"a":          true,
+	"annotation": true,
+	"b":          true,
+	"big":        true,
+	"font":       true,
+	"i":          true,
+	"s":          true,
+	"small":      true,
+	"strike":     true,
+	"sub":        true,
+	"sup":        true,
+	"tt":         true,
+	"u":          true,
+	"xliff:g":    true,
+}
+
+// markupTag holds a single inline tag found in a value
+type markupTag struct {
+	name        string
+	attributes  string
+	closing     bool
+	selfClosing bool
+	start       int
+	end         int
+}
+
+// parseMarkup returns the inline tags found in value. Comments and CDATA sections
+// are skipped. If the last tag isn't terminated, ok is false.
+func parseMarkup(value string) (tags []markupTag, ok bool) {
+
+	for i := 0; i < len(value); i++ {
+		if value[i] != '<' {
+			continue
+		}
+
+		// Skip comments and CDATA sections
+		if skip := markupSkip(value[i:]); skip > 0 {
+			i += skip - 1
+			continue
+		} else if skip < 0 {
+			return tags, false
+		}
+
+		end := strings.IndexByte(value[i:], '>')
+		if end < 0 {
+			return tags, false
+		}
+		end += i
+
+		tag := markupTag{start: i, end: end + 1}
+		body := value[i+1 : end]
+		if strings.HasPrefix(body, "/") {
+			tag.closing = true
+			body = body[1:]
+		}
+		if strings.HasSuffix(body, "/") {
+			tag.selfClosing = true
+			body = body[:len(body)-1]
+		}
+		body = trimSpace(body)
+		if sep := strings.IndexAny(body, " \t\r\n"); sep >= 0 {
+			tag.name = strings.ToLower(body[:sep])
+			tag.attributes = trimSpace(body[sep:])
+		} else {
+			tag.name = strings.ToLower(body)
+		}
+		tags = append(tags, tag)
+		i = end
+	}
+	return tags, true
+}
+
+// markupSkip returns the length of a comment or CDATA section at the start of
+// value, 0 if there is none, or -1 if it isn't terminated
+func markupSkip(value string) int {
+	for _, pair := range [][2]string{{"<!--", "-->"}, {"<![CDATA[", "]]>"}} {
+		if !strings.HasPrefix(value, pair[0]) {
+			continue
+		}
+		end := strings.Index(value[len(pair[0]):], pair[1])
+		if end < 0 {
+			return -1
+		}
+		return len(pair[0]) + end + len(pair[1])
+	}
+	return 0
+}
+
+// validateMarkup returns the problems found in the inline markup of value, like
+// unknown tags and tags that are not balanced
+func validateMarkup(value string) (problems []string) {
+
+	tags, ok := parseMarkup(value)
+	if !ok {
+		problems = append(problems, "unterminated tag")
+	}
+
+	var open []string
+	for _, tag := range tags {
+		if !markupTags[tag.name] {
+			if !tag.closing {
+				problems = append(problems, fmt.Sprintf("unknown tag <%s>", tag.name))
+			}
+			continue
+		}
+
+		switch {
+		case tag.selfClosing:
+		case tag.closing:
+			if len(open) == 0 || open[len(open)-1] != tag.name {
+				problems = append(problems, fmt.Sprintf("unexpected closing tag </%s>", tag.name))
+				continue
+			}
+			open = open[:len(open)-1]
+		default:
+			open = append(open, tag.name)
+		}
+	}
+
+	for _, name := range open {
+		problems = append(problems, fmt.Sprintf("tag <%s> is not closed", name))
+	}
+	return problems
+}
+
+// markupSignature returns the sorted names of the opening tags in value. Two values
+// with equal signatures use the same set of tags.
+func markupSignature(value string) string {
+	tags, _ := parseMarkup(value)
+	var names []string
+	for _, tag := range tags {
+		if !tag.closing {
+			names = append(names, "<"+tag.name+">")
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+// CheckMarkup validates the inline markup of all values and compares the tags used
+// with the tags of the source values. src may be nil, in which case the markup is
+// only validated.
+func (res *Resources) CheckMarkup(src *Resources) (issues []Issue) {
+
+	for _, key := range res.sortedElementKeys() {
+		var srcElement Elementer
+		if src != nil {
+			srcElement = src.Elements[key]
+		}
+
+		forEachText(res.Elements[key], srcElement, func(where, value, source string, hasSource bool) {
+			for _, problem := range validateMarkup(value) {
+				issues = append(issues, Issue{
+					FilePath: res.FilePath,
+					Key:      key,
+					Message:  where + problem,
+				})
+			}
+
+			if !hasSource {
+				return
+			}
+			if got, want := markupSignature(value), markupSignature(source); got != want {
+				issues = append(issues, Issue{
+					FilePath: res.FilePath,
+					Key:      key,
+					Message:  fmt.Sprintf("%stags [%s] differ from source [%s]", where, got, want),
+				})
+			}
+		})
+	}
+	return issues
+}
+
+// forEachText calls fn for every text of element together with the matching text of
+// srcElement. Array items are matched by index, plurals items by quantity with a
+// fallback to quantity other. where describes the location of the text within the
+// element, and is empty for strings. srcElement may be nil.
+func forEachText(element, srcElement Elementer, fn func(where, value, source string, hasSource bool)) {
+
+	switch e := element.(type) {
+	case *ElementStrings:
+		if s, ok := srcElement.(*ElementStrings); ok {
+			fn("", e.value, s.value, true)
+			return
+		}
+		fn("", e.value, "", false)
+
+	case *ElementArrays:
+		s, _ := srcElement.(*ElementArrays)
+		for index, item := range e.items {
+			where := fmt.Sprintf("item %d: ", index)
+			if s != nil && index < len(s.items) {
+				fn(where, item, s.items[index], true)
+				continue
+			}
+			fn(where, item, "", false)
+		}
+
+	case *ElementPlurals:
+		s, _ := srcElement.(*ElementPlurals)
+		for index, item := range e.items {
+			where := fmt.Sprintf("quantity %s: ", e.quantities[index])
+			if s != nil {
+				if source, ok := s.quantity(e.quantities[index]); ok {
+					fn(where, item, source, true)
+					continue
+				}
+				if source, ok := s.quantity("other"); ok {
+					fn(where, item, source, true)
+					continue
+				}
+			}
+			fn(where, item, "", false)
+		}
+	}
+}
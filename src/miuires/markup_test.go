@@ -0,0 +1,108 @@
+package miuires
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMarkup(t *testing.T) {
+
+	tests := []struct {
+		value  string
+		want   []string
+		wantOK bool
+	}{
+		{value: "Plain text", wantOK: true},
+		{value: "<b>Bold</b>", want: []string{"b", "/b"}, wantOK: true},
+		{value: "Line<br/>break", want: []string{"br/"}, wantOK: true},
+		{
+			value:  `<xliff:g id="count" example="3">%d</xliff:g> files`,
+			want:   []string{"xliff:g", "/xliff:g"},
+			wantOK: true,
+		},
+		{value: "<B>Upper</B>", want: []string{"b", "/b"}, wantOK: true},
+		{value: "<!-- <b> -->text", wantOK: true},
+		{value: "<![CDATA[<b>]]><i>x</i>", want: []string{"i", "/i"}, wantOK: true},
+		{value: "<b>Open", want: []string{"b"}, wantOK: true},
+		{value: "<b>Bold</b", want: []string{"b"}, wantOK: false},
+		{value: "<!-- open comment", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		tags, ok := parseMarkup(tt.value)
+		var got []string
+		for _, tag := range tags {
+			name := tag.name
+			if tag.closing {
+				name = "/" + name
+			}
+			if tag.selfClosing {
+				name += "/"
+			}
+			got = append(got, name)
+		}
+		if !reflect.DeepEqual(got, tt.want) || ok != tt.wantOK {
+			t.Errorf("%q: got %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestParseMarkupAttributes(t *testing.T) {
+
+	value := `<a href="https://example.com">link</a>`
+	tags, ok := parseMarkup(value)
+	if !ok || len(tags) != 2 {
+		t.Fatalf("got %v, %v, want 2 tags", tags, ok)
+	}
+	if tags[0].attributes != `href="https://example.com"` {
+		t.Errorf("got attributes %q", tags[0].attributes)
+	}
+	if got := value[tags[0].start:tags[0].end]; got != `<a href="https://example.com">` {
+		t.Errorf("got range %q", got)
+	}
+}
+
+func TestValidateMarkup(t *testing.T) {
+
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "<b>Bold</b> and <i>italic</i>"},
+		{value: "<b><i>Nested</i></b>"},
+		{value: "<b>Open", want: []string{"tag <b> is not closed"}},
+		{value: "Close</b>", want: []string{"unexpected closing tag </b>"}},
+		{value: "<b><i>Crossed</b></i>", want: []string{
+			"unexpected closing tag </b>",
+			"tag <b> is not closed",
+		}},
+		{value: "<blink>x</blink>", want: []string{"unknown tag <blink>"}},
+		{value: "<b>x</b", want: []string{"unterminated tag", "tag <b> is not closed"}},
+	}
+
+	for _, tt := range tests {
+		if got := validateMarkup(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMarkupSignature(t *testing.T) {
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "Plain", want: ""},
+		{value: "<i>a</i><b>b</b>", want: "<b> <i>"},
+		{value: "<b>a</b><b>b</b>", want: "<b> <b>"},
+		{value: "<br/>", want: "<br>"},
+		{value: "<!-- <u> --><b>x</b>", want: "<b>"},
+	}
+
+	for _, tt := range tests {
+		if got := markupSignature(tt.value); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package miuires
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// quarantineSuffix is the suffix of sibling files holding quarantined elements
+const quarantineSuffix = ".filtered.xml"
+
+// QuarantinePath returns the path of the sibling file that holds the elements removed
+// from the resources at filePath, for example strings.filtered.xml for strings.xml
+func QuarantinePath(filePath string) string {
+	return strings.TrimSuffix(filePath, ".xml") + quarantineSuffix
+}
+
+// QuarantineTarget returns the path of the resources a sibling quarantine file at
+// quarantinePath belongs to, for example strings.xml for strings.filtered.xml
+func QuarantineTarget(quarantinePath string) string {
+	return strings.TrimSuffix(quarantinePath, quarantineSuffix) + ".xml"
+}
+
+// IsQuarantinePath returns true if filePath is a sibling file holding quarantined elements
+func IsQuarantinePath(filePath string) bool {
+	switch filepath.Base(filePath) {
+	case "strings" + quarantineSuffix, "arrays" + quarantineSuffix, "plurals" + quarantineSuffix:
+		return true
+	}
+	return false
+}
+
+// getFileType returns the file type of the resources at filePath. Quarantine files
+// have the file type of the resources they belong to.
+func getFileType(filePath string) string {
+	base := filepath.Base(filePath)
+	if strings.HasSuffix(base, quarantineSuffix) {
+		return strings.TrimSuffix(base, quarantineSuffix) + ".xml"
+	}
+	return base
+}
+
+// WriteQuarantine writes the elements removed by Filter to path in the same format as
+// the resources. Elements quarantined before are kept. Nothing is written if no
+// elements were removed.
+func (res *Resources) WriteQuarantine(path string) error {
+
+	if len(res.Removed) == 0 {
+		return nil
+	}
+
+	quarantine := &Resources{
+		FilePath: path,
+		FileType: res.FileType,
+		AppName:  res.AppName,
+		Locale:   res.Locale,
+		Elements: make(map[string]Elementer),
+	}
+
+	// Merge with elements quarantined before
+	if _, err := os.Stat(path); err == nil {
+		if quarantine, err = NewResources(path); err != nil {
+			return err
+		}
+	} else if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	for _, removal := range res.Removed {
+		if removal.element != nil {
+			quarantine.Elements[removal.Key] = removal.element
+		}
+	}
+	quarantine.Keys = quarantine.sortedElementKeys()
+	return quarantine.Write()
+}
+
+// Restore merges the quarantined elements back into the resources. Elements that
+// are present in the resources are not overwritten. It returns the number of
+// restored elements.
+func (res *Resources) Restore(quarantine *Resources) (restored int) {
+	for key, element := range quarantine.Elements {
+		if _, ok := res.Elements[key]; ok {
+			continue
+		}
+		res.Elements[key] = element
+		restored++
+	}
+	res.Keys = res.sortedElementKeys()
+	return restored
+}
@@ -6,7 +6,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"runtime"
 	"sort"
 	"strings"
 )
@@ -16,33 +15,66 @@ type Resources struct {
 	FilePath string
 	FileType string
 	AppName  string
+	Locale   string
 	Keys     []string
 	Elements map[string]Elementer
 	Comment  string
+	Warnings []string
+	Removed  []Removal
+
+	// Style is the indentation style used by Write, StyleSpaces if empty
+	Style string
+
+	// order holds the keys in the order they were found in the file
+	order []string
 }
 
-// NewResources returns new unloaded resources
-func NewResources(filePath string) (res *Resources, err error) {
+// styleIndents holds the indentation of each output style
+var styleIndents = map[string]string{
+	StyleSpaces:  "    ",
+	StyleCompact: "  ",
+	StyleTabs:    "\t",
+}
 
-	// Get app name
-	var appName string
-	separator := "/"
-	if runtime.GOOS == "windows" {
-		separator = `\`
+// Removal records an element that was removed by a filter, and the rule that
+// triggered the removal
+type Removal struct {
+	FilePath string `json:"file"`
+	Key      string `json:"key"`
+	Section  string `json:"section"`
+	App      string `json:"app,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+	Match    string `json:"match,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+
+	// element holds the removed element, so it can be quarantined
+	element Elementer
+}
+
+// String returns the removal formatted as a single line
+func (r Removal) String() string {
+	rule := r.Section
+	if r.Locale != "" {
+		rule = "locales: " + r.Locale + ": " + rule
 	}
-	slice := strings.Split(filePath, separator)
-	for _, p := range slice {
-		if strings.HasSuffix(p, ".apk") {
-			appName = p
-			break
-		}
+	if r.App != "" {
+		rule += ": " + r.App
+	}
+	if r.Mode != "" {
+		rule += fmt.Sprintf(": %s %q", r.Mode, r.Match)
 	}
+	return fmt.Sprintf("%s: %s: removed by %s", r.FilePath, r.Key, rule)
+}
+
+// NewResources returns new unloaded resources
+func NewResources(filePath string) (res *Resources, err error) {
 
 	// Create resources
 	res = &Resources{
 		FilePath: filePath,
-		FileType: filepath.Base(filePath),
-		AppName:  appName,
+		FileType: getFileType(filePath),
+		AppName:  getAppName(filePath),
+		Locale:   getLocale(filepath.Base(filepath.Dir(filePath))),
 		Keys:     []string{},
 		Elements: make(map[string]Elementer),
 	}
@@ -97,7 +129,14 @@ func (res *Resources) load() (err error) {
 	elements = append(elements, elementPlaceholder)
 
 	// We put every string in a map. This makes sure we have unique keys.
-	// This way we remove double string items.
+	// This way we remove double string items. The order of first appearance is kept.
+	add := func(element Elementer) {
+		if _, ok := res.Elements[element.GetName()]; !ok {
+			res.order = append(res.order, element.GetName())
+		}
+		res.Elements[element.GetName()] = element
+	}
+
 	for _, v := range elements {
 
 		// Handle comment
@@ -109,15 +148,22 @@ func (res *Resources) load() (err error) {
 		switch res.FileType {
 		case FileTypeArrays:
 			if ok, element := NewArrays(v); ok {
-				res.Elements[element.GetName()] = element
+				add(element)
 			}
 		case FileTypePlurals:
 			if ok, element := NewPlurals(v); ok {
-				res.Elements[element.GetName()] = element
+				duplicates, err := element.normalize()
+				if err != nil {
+					return fmt.Errorf("%s: %v", res.FilePath, err)
+				}
+				for _, quantity := range duplicates {
+					res.Warnings = append(res.Warnings, fmt.Sprintf("%s: plurals %s has duplicate quantity %q, keeping the last one", res.FilePath, element.GetName(), quantity))
+				}
+				add(element)
 			}
 		case FileTypeStrings:
 			if ok, element := NewStrings(v); ok {
-				res.Elements[element.GetName()] = element
+				add(element)
 			}
 		}
 	}
@@ -132,133 +178,229 @@ func (res *Resources) load() (err error) {
 	return nil
 }
 
-// Filter filters the resources using FilterConfig
-func (res *Resources) Filter(fc *FilterConfig) error {
+// SortKeys orders the keys of the resources, which is the order in which Write writes
+// the elements. SortKey orders them by key, SortFile keeps the order of the file.
+func (res *Resources) SortKeys(mode string) error {
+	switch mode {
+	case SortKey:
+		sort.Strings(res.Keys)
+	case SortFile:
+		res.Keys = append([]string{}, res.order...)
+	default:
+		return fmt.Errorf("unknown sort mode %q, use %s or %s", mode, SortKey, SortFile)
+	}
+	return nil
+}
 
-	for elementKey, element := range res.Elements {
+// Filter filters the resources using FilterConfig. An element is removed when it is
+// matched by one of the removal rules, unless its key is matched by a keep rule.
+// Rules scoped to the locale of the resources are applied in addition to the
+// general rules. Every removal is recorded in res.Removed.
+func (res *Resources) Filter(fc *FilterConfig) error {
 
-		switch res.FileType {
-		case FileTypeStrings:
-			// Filter general key rules
-			if rules, ok := fc.StringsKeyRules["all"]; ok {
-				res.filterKey(rules, elementKey)
-			}
+	configs := fc.scoped(res.Locale)
+	for _, elementKey := range res.sortedElementKeys() {
+		element := res.Elements[elementKey]
 
-			// Filter application key rules
-			if rules, ok := fc.StringsKeyRules[res.AppName]; ok {
-				res.filterKey(rules, elementKey)
+		var removal Removal
+		var remove, keep bool
+		for _, c := range configs {
+			if !remove {
+				removal, remove = res.removable(c, elementKey, element)
 			}
+			keep = keep || res.keep(c, elementKey)
+		}
+		if remove && !keep {
+			delete(res.Elements, elementKey)
+			removal.element = element
+			res.Removed = append(res.Removed, removal)
+		}
+	}
+	return nil
+}
 
-			// Filter general value rules
-			if rules, ok := fc.StringsValueRules["all"]; ok {
-				res.filterValue(rules, elementKey, element.GetValue())
-			}
+// FilterUntranslatable removes the elements that are marked translatable="false" in
+// the untranslated source resources src. Every removal is recorded in res.Removed.
+func (res *Resources) FilterUntranslatable(src *Resources) {
 
-			// Filter application value rules
-			if rules, ok := fc.StringsValueRules[res.AppName]; ok {
-				res.filterValue(rules, elementKey, element.GetValue())
-			}
+	for _, elementKey := range res.sortedElementKeys() {
+		srcElement, ok := src.Elements[elementKey]
+		if !ok {
+			continue
+		}
+		if translatable, ok := srcElement.GetAttribute("translatable"); !ok || translatable != "false" {
+			continue
+		}
 
-		case FileTypeArrays:
-			// Filter general key rules
-			if rules, ok := fc.ArraysKeyRules["all"]; ok {
-				res.filterKey(rules, elementKey)
-			}
+		res.Removed = append(res.Removed, Removal{
+			FilePath: res.FilePath,
+			Key:      elementKey,
+			Section:  "source",
+			Match:    "false",
+			Mode:     FilterOnAttribute + "translatable",
+			element:  res.Elements[elementKey],
+		})
+		delete(res.Elements, elementKey)
+	}
+}
 
-			// Filter application key rules
-			if rules, ok := fc.ArraysKeyRules[res.AppName]; ok {
-				res.filterKey(rules, elementKey)
-			}
+// FilterIdentical removes or reports the elements that are identical to the untranslated
+// source resources src, as configured for the locale of the resources by fc. Elements
+// matched by the identical_allow_rules are kept. Reported elements are added to
+// res.Warnings.
+func (res *Resources) FilterIdentical(fc *FilterConfig, src *Resources) {
 
-			// Filter general value rules
-			if rules, ok := fc.ArraysValueRules["all"]; ok {
-				res.filterItems(rules, elementKey, element.GetItems())
-			}
+	mode := fc.IdenticalModeFor(res.Locale)
+	if mode == "" {
+		return
+	}
 
-			// Filter application value rules
-			if rules, ok := fc.ArraysValueRules[res.AppName]; ok {
-				res.filterItems(rules, elementKey, element.GetItems())
-			}
+	configs := fc.scoped(res.Locale)
+	for _, elementKey := range res.sortedElementKeys() {
+		element := res.Elements[elementKey]
+		if !isIdentical(element, src.Elements[elementKey]) {
+			continue
+		}
 
-		case FileTypePlurals:
-			// Filter general key rules
-			if rules, ok := fc.PluralsKeyRules["all"]; ok {
-				res.filterKey(rules, elementKey)
+		compound := func(rule *FilterRules) bool { return rule.matchesElement(elementKey, element) }
+		var allowed bool
+		for _, c := range configs {
+			if _, ok := res.match(c, "identical_allow_rules", elementKey, compound); ok {
+				allowed = true
+				break
 			}
+		}
+		if allowed {
+			continue
+		}
 
-			// Filter application key rules
-			if rules, ok := fc.PluralsKeyRules[res.AppName]; ok {
-				res.filterKey(rules, elementKey)
-			}
+		if mode == IdenticalReport {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("%s: %s: translation is identical to the source", res.FilePath, elementKey))
+			continue
 		}
+
+		res.Removed = append(res.Removed, Removal{
+			FilePath: res.FilePath,
+			Key:      elementKey,
+			Section:  "identical",
+			element:  element,
+		})
+		delete(res.Elements, elementKey)
 	}
-	return nil
 }
 
-func (res *Resources) filterKey(rules []FilterRules, elementKey string) {
-	for _, rule := range rules {
-		switch rule.Mode {
-		case FilterModeSuffix:
-			if strings.HasSuffix(elementKey, rule.Match) {
-				delete(res.Elements, elementKey)
-			}
-		case FilterModePrefix:
-			if strings.HasPrefix(elementKey, rule.Match) {
-				delete(res.Elements, elementKey)
-			}
-		case FilterModeContains:
-			if strings.Contains(elementKey, rule.Match) {
-				delete(res.Elements, elementKey)
+// isIdentical returns true if element has the same non empty value or items as srcElement
+func isIdentical(element Elementer, srcElement Elementer) bool {
+	if srcElement == nil {
+		return false
+	}
+
+	switch e := element.(type) {
+	case *ElementStrings:
+		return e.value != "" && e.value == srcElement.GetValue()
+
+	case *ElementArrays, *ElementPlurals:
+		items, srcItems := e.GetItems(), srcElement.GetItems()
+		if len(items) == 0 || len(items) != len(srcItems) {
+			return false
+		}
+		for i := range items {
+			if items[i] != srcItems[i] {
+				return false
 			}
 		}
+		if ep, ok := e.(*ElementPlurals); ok {
+			srcPlurals, ok := srcElement.(*ElementPlurals)
+			return ok && strings.Join(ep.quantities, ",") == strings.Join(srcPlurals.quantities, ",")
+		}
+		return true
 	}
+	return false
 }
 
-func (res *Resources) filterValue(rules []FilterRules, elementKey string, elementValue string) {
-	for _, rule := range rules {
-		switch rule.Mode {
-		case FilterModeSuffix:
-			if strings.HasSuffix(elementValue, rule.Match) {
-				delete(res.Elements, elementKey)
-				return
-			}
-		case FilterModePrefix:
-			if strings.HasPrefix(elementValue, rule.Match) {
-				delete(res.Elements, elementKey)
-				return
-			}
-		case FilterModeContains:
-			if strings.Contains(elementValue, rule.Match) {
-				delete(res.Elements, elementKey)
-				return
-			}
-		default:
+// removable returns the removal for the first removal rule that matches the element
+func (res *Resources) removable(fc *FilterConfig, elementKey string, element Elementer) (Removal, bool) {
+
+	key := func(rule *FilterRules) bool { return rule.matches(elementKey) }
+	value := func(rule *FilterRules) bool { return rule.matches(element.GetValue()) }
+	items := func(rule *FilterRules) bool { return rule.matchesItems(element.GetItems()) }
+	compound := func(rule *FilterRules) bool { return rule.matchesElement(elementKey, element) }
+
+	var sections []string
+	var matchers []func(rule *FilterRules) bool
+
+	switch res.FileType {
+	case FileTypeStrings:
+		sections = []string{"strings_key_rules", "strings_value_rules", "strings_rules"}
+		matchers = append(matchers, key, value, compound)
+
+	case FileTypeArrays:
+		// Remove integer arrays
+		if ea, ok := element.(*ElementArrays); ok && fc.removesIntegerArrays() && ea.form == "integer-array" {
+			return Removal{
+				FilePath: res.FilePath,
+				Key:      elementKey,
+				Section:  "remove_integer_arrays",
+				Locale:   fc.locale,
+			}, true
+		}
+		sections = []string{"arrays_key_rules", "arrays_value_rules", "arrays_rules"}
+		matchers = append(matchers, key, items, compound)
+
+	case FileTypePlurals:
+		sections = []string{"plurals_key_rules", "plurals_value_rules", "plurals_rules"}
+		matchers = append(matchers, key, items, compound)
+	}
+
+	for index, section := range sections {
+		if removal, ok := res.match(fc, section, elementKey, matchers[index]); ok {
+			return removal, true
 		}
 	}
+	return Removal{}, false
 }
 
-func (res *Resources) filterItems(rules []FilterRules, elementKey string, items []string) {
-	for _, rule := range rules {
-		for _, item := range items {
-			switch rule.Mode {
-			case FilterModeSuffix:
-				if strings.HasSuffix(item, rule.Match) {
-					delete(res.Elements, elementKey)
-					return
-				}
-			case FilterModePrefix:
-				if strings.HasPrefix(item, rule.Match) {
-					delete(res.Elements, elementKey)
-					return
-				}
-			case FilterModeContains:
-				if strings.Contains(item, rule.Match) {
-					delete(res.Elements, elementKey)
-					return
-				}
-			}
+// keep returns true if the key is matched by one of the keep rules
+func (res *Resources) keep(fc *FilterConfig, elementKey string) bool {
+
+	key := func(rule *FilterRules) bool { return rule.matches(elementKey) }
+
+	var ok bool
+	switch res.FileType {
+	case FileTypeStrings:
+		_, ok = res.match(fc, "strings_keep_rules", elementKey, key)
+	case FileTypeArrays:
+		_, ok = res.match(fc, "arrays_keep_rules", elementKey, key)
+	case FileTypePlurals:
+		_, ok = res.match(fc, "plurals_keep_rules", elementKey, key)
+	}
+	return ok
+}
+
+// match returns a removal for the first rule in section of the filter configuration
+// that is matched according to matcher
+func (res *Resources) match(fc *FilterConfig, section string, elementKey string, matcher func(rule *FilterRules) bool) (Removal, bool) {
+	for _, rule := range fc.rules(*fc.section(section), res.AppName) {
+		if !matcher(&rule.FilterRules) {
+			continue
 		}
+
+		removal := Removal{
+			FilePath: res.FilePath,
+			Key:      elementKey,
+			Section:  section,
+			App:      rule.app,
+			Locale:   fc.locale,
+			Match:    rule.Match,
+			Mode:     rule.Mode,
+		}
+		if rule.isCompound() || rule.On != "" {
+			removal.Match = rule.String()
+			removal.Mode = FilterModeCompound
+		}
+		return removal, true
 	}
+	return Removal{}, false
 }
 
 // Write writes resources to res.FilePath
@@ -281,12 +423,17 @@ func (res *Resources) Write() error {
 		io.WriteString(f, comment)
 	}
 
+	indent, ok := styleIndents[res.Style]
+	if !ok {
+		indent = defaultIndent
+	}
+
 	io.WriteString(f, fmt.Sprintf("<resources>\n"))
 
 	for _, key := range res.Keys {
 
 		if val, ok := res.Elements[key]; ok {
-			f.Write(val.Write())
+			f.Write(val.WriteIndent(indent))
 		}
 	}
 
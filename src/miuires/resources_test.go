@@ -0,0 +1,98 @@
+package miuires
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newTestStrings returns strings.xml resources of locale with the values by key
+func newTestStrings(locale string, values map[string]string) *Resources {
+	res := &Resources{
+		FilePath: "Settings.apk/res/values-" + locale + "/strings.xml",
+		FileType: FileTypeStrings,
+		AppName:  "Settings.apk",
+		Locale:   locale,
+		Elements: make(map[string]Elementer),
+	}
+	for key, value := range values {
+		res.Elements[key] = &ElementStrings{name: key, value: value}
+	}
+	res.Keys = res.sortedElementKeys()
+	return res
+}
+
+func TestFilterIdentical(t *testing.T) {
+
+	src := newTestStrings("", map[string]string{"brand": "MIUI", "title": "Settings"})
+
+	tests := []struct {
+		name        string
+		config      string
+		locale      string
+		wantRemoved []string
+		wantWarned  int
+	}{
+		{
+			name:        "general setting",
+			config:      "identical: remove\n",
+			locale:      "nl",
+			wantRemoved: []string{"brand", "title"},
+		},
+		{
+			name:   "unset",
+			config: "strings_key_rules:\n",
+			locale: "nl",
+		},
+		{
+			name:        "locale setting",
+			config:      "locales:\n  nl:\n    identical: remove\n",
+			locale:      "nl",
+			wantRemoved: []string{"brand", "title"},
+		},
+		{
+			name:   "setting of another locale",
+			config: "locales:\n  de:\n    identical: remove\n",
+			locale: "nl",
+		},
+		{
+			name:       "locale overrides language and general setting",
+			config:     "identical: remove\nlocales:\n  zh:\n    identical: remove\n  zh-rCN:\n    identical: report\n",
+			locale:     "zh-rCN",
+			wantWarned: 2,
+		},
+		{
+			name:   "locale clears general setting",
+			config: "identical: remove\nlocales:\n  nl:\n    identical: \"\"\n",
+			locale: "nl",
+		},
+		{
+			name:        "allow rules keep identical translations",
+			config:      "identical: remove\nidentical_allow_rules:\n  all:\n    - on: key\n      match: brand\n      mode: exact\n",
+			locale:      "nl",
+			wantRemoved: []string{"title"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fc, err := decodeFilterConfig([]byte(test.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := newTestStrings(test.locale, map[string]string{"brand": "MIUI", "title": "Settings", "other": "Anders"})
+			res.FilterIdentical(fc, src)
+
+			var removed []string
+			for _, removal := range res.Removed {
+				removed = append(removed, removal.Key)
+			}
+			if !reflect.DeepEqual(removed, test.wantRemoved) {
+				t.Errorf("got removed %q, want %q", removed, test.wantRemoved)
+			}
+			if len(res.Warnings) != test.wantWarned {
+				t.Errorf("got warnings %q, want %d", res.Warnings, test.wantWarned)
+			}
+		})
+	}
+}
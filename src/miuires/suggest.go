@@ -0,0 +1,172 @@
+package miuires
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Suggestion holds an element that is likely untranslatable, and the reason why
+type Suggestion struct {
+	AppName  string
+	FileType string
+	Key      string
+	Reason   string
+}
+
+// untranslatablePatterns holds patterns of values that are likely untranslatable,
+// in the order they are tried
+var untranslatablePatterns = []struct {
+	reason  string
+	pattern *regexp.Regexp
+}{
+	{"url", regexp.MustCompile(`^((https?|ftp)://|www\.)\S+$`)},
+	{"email", regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[a-zA-Z]{2,}$`)},
+	{"resource reference", regexp.MustCompile(`^[@?]([\w.]+:)?[\w]+/[\w.]+$`)},
+	{"package name", regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-zA-Z][a-zA-Z0-9_]*){2,}$`)},
+	{"color", regexp.MustCompile(`^#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)},
+	{"number", regexp.MustCompile(`^[-+]?[0-9]+([.,][0-9]+)*$`)},
+}
+
+// formatSpecifierPattern matches format specifiers like %s, %1$d and %.2f
+var formatSpecifierPattern = regexp.MustCompile(`%(\d+\$)?[-#+ 0,(]*\d*(\.\d+)?[a-zA-Z%]`)
+
+// untranslatableReason returns why value is likely untranslatable, or an empty string
+// if it is probably translatable
+func untranslatableReason(value string) string {
+	value = trimSpace(value)
+	if value == "" {
+		return ""
+	}
+
+	for _, p := range untranslatablePatterns {
+		if p.pattern.MatchString(value) {
+			return p.reason
+		}
+	}
+
+	// Values that only hold format specifiers, spaces and punctuation
+	if formatSpecifierPattern.MatchString(value) {
+		rest := formatSpecifierPattern.ReplaceAllString(value, "")
+		if strings.IndexFunc(rest, unicode.IsLetter) < 0 {
+			return "format only"
+		}
+	}
+	return ""
+}
+
+// SuggestUntranslatable returns the elements of the resources that are likely
+// untranslatable. Arrays and plurals are only suggested if all of their items are
+// likely untranslatable.
+func (res *Resources) SuggestUntranslatable() (suggestions []Suggestion) {
+
+	for _, key := range res.sortedElementKeys() {
+		element := res.Elements[key]
+
+		var reason string
+		switch res.FileType {
+		case FileTypeStrings:
+			reason = untranslatableReason(element.GetValue())
+		case FileTypeArrays, FileTypePlurals:
+			for _, item := range element.GetItems() {
+				if reason = untranslatableReason(item); reason == "" {
+					break
+				}
+			}
+		}
+
+		if reason != "" {
+			suggestions = append(suggestions, Suggestion{
+				AppName:  res.AppName,
+				FileType: res.FileType,
+				Key:      key,
+				Reason:   reason,
+			})
+		}
+	}
+	return suggestions
+}
+
+// SuggestFilterConfig returns a filter configuration in YAML with an exact key rule for
+// every suggestion, grouped per application. The reason of each suggestion is added
+// as a comment. Duplicate suggestions are only included once.
+func SuggestFilterConfig(suggestions []Suggestion) ([]byte, error) {
+
+	sections := map[string]string{
+		FileTypeStrings: "strings_key_rules",
+		FileTypeArrays:  "arrays_key_rules",
+		FileTypePlurals: "plurals_key_rules",
+	}
+
+	// Group suggestions per section and application
+	grouped := make(map[string]map[string]map[string]string)
+	for _, s := range suggestions {
+		section := sections[s.FileType]
+		if grouped[section] == nil {
+			grouped[section] = make(map[string]map[string]string)
+		}
+		if grouped[section][s.AppName] == nil {
+			grouped[section][s.AppName] = make(map[string]string)
+		}
+		grouped[section][s.AppName][s.Key] = s.Reason
+	}
+
+	document := &yaml.Node{Kind: yaml.MappingNode}
+	for _, section := range []string{"strings_key_rules", "arrays_key_rules", "plurals_key_rules"} {
+		apps, ok := grouped[section]
+		if !ok {
+			continue
+		}
+
+		appsNode := &yaml.Node{Kind: yaml.MappingNode}
+		appNames := make([]string, 0, len(apps))
+		for app := range apps {
+			appNames = append(appNames, app)
+		}
+		sort.Strings(appNames)
+
+		for _, app := range appNames {
+			keys := make([]string, 0, len(apps[app]))
+			for key := range apps[app] {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			rulesNode := &yaml.Node{Kind: yaml.SequenceNode}
+			for _, key := range keys {
+				match := scalarNode(key)
+				match.LineComment = apps[app][key]
+				rulesNode.Content = append(rulesNode.Content, &yaml.Node{
+					Kind: yaml.MappingNode,
+					Content: []*yaml.Node{
+						scalarNode("match"), match,
+						scalarNode("mode"), scalarNode(FilterModeExact),
+					},
+				})
+			}
+			appsNode.Content = append(appsNode.Content, scalarNode(app), rulesNode)
+		}
+		document.Content = append(document.Content, scalarNode(section), appsNode)
+	}
+
+	if len(document.Content) == 0 {
+		return []byte{}, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(document); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// scalarNode returns a YAML scalar node holding value
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
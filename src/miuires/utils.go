@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"runtime"
 	"strings"
 )
 
@@ -28,8 +29,18 @@ func trimSpace(base string) string {
 
 func fixApostrophe(base string) (fixed string) {
 
+	// Apostrophes within markup tags, like attribute values, are left untouched. Text
+	// between tags, including the text of xliff:g spans, is fixed as usual.
+	protected := protectedRanges(base)
+	apostropheIndex := -1
+	for i := 0; i < len(base); i++ {
+		if base[i] == 39 && !inRanges(protected, i) {
+			apostropheIndex = i
+			break
+		}
+	}
+
 	// If there are no apostrophes, return base
-	apostropheIndex := strings.IndexByte(base, 39)
 	if apostropheIndex < 0 {
 		return base
 	}
@@ -45,18 +56,72 @@ func fixApostrophe(base string) (fixed string) {
 	}
 
 	// We fix the apostrophe's by escaping it with a backslash
-	splits := strings.Split(base, "'")
-	firstParsed := false
-	for _, split := range splits {
-		if !firstParsed {
-			firstParsed = true
-			fixed = split
-			continue
+	var buf strings.Builder
+	for i := 0; i < len(base); i++ {
+		if base[i] == 39 && !inRanges(protected, i) {
+			buf.WriteByte(92)
+		}
+		buf.WriteByte(base[i])
+	}
+	return buf.String()
+}
+
+// protectedRanges returns the byte ranges of base that must not be altered when
+// formatting, which are the markup tags including their attributes
+func protectedRanges(base string) (ranges [][2]int) {
+	if strings.IndexByte(base, '<') < 0 {
+		return nil
+	}
+	tags, _ := parseMarkup(base)
+	for _, tag := range tags {
+		ranges = append(ranges, [2]int{tag.start, tag.end})
+	}
+	return ranges
+}
+
+// inRanges returns true if index is within one of ranges
+func inRanges(ranges [][2]int, index int) bool {
+	for _, r := range ranges {
+		if index >= r[0] && index < r[1] {
+			return true
 		}
-		fixed = fixed + `\'` + split
 	}
+	return false
+}
 
-	return fixed
+// getAppName extracts the name of the application from the path of its resources,
+// which is the name of the .apk or .jar directory, or framework-res
+func getAppName(filePath string) (appName string) {
+	separator := "/"
+	if runtime.GOOS == "windows" {
+		separator = `\`
+	}
+	slice := strings.Split(filePath, separator)
+	for _, p := range slice {
+		if strings.HasSuffix(p, ".apk") || strings.HasSuffix(p, ".jar") || p == "framework-res" {
+			appName = p
+			break
+		}
+	}
+	return appName
+}
+
+// getLocale extracts the locale from the name of a values directory, for example
+// nl from values-nl and zh-rCN from values-zh-rCN. An empty string is returned
+// for the default values directory.
+func getLocale(dir string) string {
+	if !strings.HasPrefix(dir, "values-") {
+		return ""
+	}
+
+	qualifiers := strings.Split(strings.TrimPrefix(dir, "values-"), "-")
+	if language := qualifiers[0]; len(language) < 2 || len(language) > 3 || strings.ToLower(language) != language {
+		return ""
+	}
+	if len(qualifiers) > 1 && len(qualifiers[1]) == 3 && qualifiers[1][0] == 'r' {
+		return qualifiers[0] + "-" + qualifiers[1]
+	}
+	return qualifiers[0]
 }
 
 // getElementParameter extracts elementer parameters, like the name of a string or
@@ -74,6 +139,30 @@ func getElementParameter(base string, parameter string) (value string) {
 	return
 }
 
+// getElementAttributes extracts all parameters of the opening tag of an element
+func getElementAttributes(base string) (attributes map[string]string) {
+	attributes = make(map[string]string)
+
+	// Only the opening tag holds attributes
+	if end := strings.IndexByte(base, '>'); end >= 0 {
+		base = base[:end]
+	}
+
+	for {
+		sep := strings.Index(base, `="`)
+		if sep < 0 {
+			return attributes
+		}
+		start := strings.LastIndexAny(base[:sep], " \t\r\n<") + 1
+		end := strings.IndexByte(base[sep+2:], '"')
+		if end < 0 {
+			return attributes
+		}
+		attributes[base[start:sep]] = base[sep+2 : sep+2+end]
+		base = base[sep+2+end+1:]
+	}
+}
+
 // getElementValue extract the elemement value
 func getElementValue(base string, suffix string) (value string) {
 	startValue := strings.IndexByte(base, '>')
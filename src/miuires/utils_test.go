@@ -0,0 +1,54 @@
+package miuires
+
+import "testing"
+
+func TestFixApostrophe(t *testing.T) {
+
+	tests := []struct {
+		base string
+		want string
+	}{
+		{base: "No apostrophes", want: "No apostrophes"},
+		{base: "Don't", want: `Don\'t`},
+		{base: "It's Bob's", want: `It\'s Bob\'s`},
+		{base: `Don\'t`, want: `Don\'t`},
+		{base: `"Don't"`, want: `"Don't"`},
+		{
+			base: `<xliff:g id="name">Bob's</xliff:g> phone`,
+			want: `<xliff:g id="name">Bob\'s</xliff:g> phone`,
+		},
+		{
+			base: `<xliff:g id="name" example='Bob'>%s</xliff:g>`,
+			want: `<xliff:g id="name" example='Bob'>%s</xliff:g>`,
+		},
+		{
+			base: `<font color='red'>Don't</font>`,
+			want: `<font color='red'>Don\'t</font>`,
+		},
+		{base: "1 < 2 isn't", want: `1 < 2 isn\'t`},
+	}
+
+	for _, tt := range tests {
+		if got := fixApostrophe(tt.base); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestProtectedRanges(t *testing.T) {
+
+	base := `Hi <b>Bob</b>`
+	ranges := protectedRanges(base)
+	if len(ranges) != 2 {
+		t.Fatalf("got %v, want 2 ranges", ranges)
+	}
+	if got := base[ranges[0][0]:ranges[0][1]]; got != "<b>" {
+		t.Errorf("got %q, want <b>", got)
+	}
+	if got := base[ranges[1][0]:ranges[1][1]]; got != "</b>" {
+		t.Errorf("got %q, want </b>", got)
+	}
+	if ranges := protectedRanges("No tags"); ranges != nil {
+		t.Errorf("got %v, want no ranges", ranges)
+	}
+}
@@ -0,0 +1,137 @@
+package miuires
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ConfigErrors holds all problems found when validating a filter configuration
+type ConfigErrors []string
+
+// Error returns the problems, one per line
+func (ce ConfigErrors) Error() string {
+	return strings.Join(ce, "\n")
+}
+
+// filterRulesFields holds the fields that are allowed in a rule
+var filterRulesFields = map[string]bool{
+	"match":  true,
+	"mode":   true,
+	"items":  true,
+	"remove": true,
+	"on":     true,
+	"all":    true,
+	"any":    true,
+	"not":    true,
+}
+
+// UnmarshalYAML decodes a rule from YAML. Unknown fields are not allowed, and the
+// line of the rule is stored so problems can be reported.
+func (fr *FilterRules) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: a rule must be a mapping with match and mode", value.Line)}}
+	}
+	for i := 0; i < len(value.Content); i += 2 {
+		if key := value.Content[i]; !filterRulesFields[key.Value] {
+			return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: unknown field %q in rule", key.Line, key.Value)}}
+		}
+	}
+
+	type plain FilterRules
+	if err := value.Decode((*plain)(fr)); err != nil {
+		return err
+	}
+	fr.line = value.Line
+	return nil
+}
+
+// withContext inserts context after the line number of a problem, like
+// "line 3: strings_key_rules: all: unknown mode"
+func withContext(context string, problem string) string {
+	if strings.HasPrefix(problem, "line ") {
+		if sep := strings.Index(problem, ": "); sep >= 0 {
+			return problem[:sep+2] + context + ": " + problem[sep+2:]
+		}
+	}
+	return context + ": " + problem
+}
+
+// isCompoundSection returns true if the section holds compound rules
+func isCompoundSection(sectionName string) bool {
+	switch sectionName {
+	case "strings_rules", "arrays_rules", "plurals_rules", "identical_allow_rules":
+		return true
+	}
+	return false
+}
+
+// isFilterOn returns true if on is a valid target of a compound rule condition
+func isFilterOn(on string) bool {
+	switch {
+	case on == FilterOnKey, on == FilterOnValue, on == FilterOnItems:
+		return true
+	case strings.HasPrefix(on, FilterOnAttribute) && len(on) > len(FilterOnAttribute):
+		return true
+	}
+	return false
+}
+
+// sortedKeys returns the application names of a section in sorted order
+func sortedKeys(section map[string][]FilterRules) []string {
+	keys := make([]string, 0, len(section))
+	for k := range section {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedLocales returns the locales of locale scoped configurations in sorted order
+func sortedLocales(locales map[string]*FilterConfig) []string {
+	keys := make([]string, 0, len(locales))
+	for k := range locales {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// valueNode returns the node found by following the keys of nested mappings from
+// node, or nil if it doesn't exist
+func valueNode(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		node = next
+	}
+	return node
+}
+
+// keyLine returns the line of the last key found by following keys from node, or 0
+// if it doesn't exist
+func keyLine(node *yaml.Node, keys ...string) int {
+	if len(keys) == 0 {
+		return 0
+	}
+	parent := valueNode(node, keys[:len(keys)-1]...)
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return 0
+	}
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == keys[len(keys)-1] {
+			return parent.Content[i].Line
+		}
+	}
+	return 0
+}
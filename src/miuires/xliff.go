@@ -0,0 +1,111 @@
+package miuires
+
+import (
+	"fmt"
+	"strings"
+)
+
+// xliffSpan holds a <xliff:g> span found in a value
+type xliffSpan struct {
+	id    string
+	inner string
+	start int
+	end   int
+}
+
+// xliffSpans returns the <xliff:g> spans found in value. Spans without a closing
+// tag are ignored, those are reported by the markup check.
+func xliffSpans(value string) (spans []xliffSpan) {
+	tags, _ := parseMarkup(value)
+	for i := 0; i < len(tags); i++ {
+		open := tags[i]
+		if open.name != "xliff:g" || open.closing || open.selfClosing {
+			continue
+		}
+		for j := i + 1; j < len(tags); j++ {
+			if tags[j].name == "xliff:g" && tags[j].closing {
+				spans = append(spans, xliffSpan{
+					id:    getElementParameter(open.attributes, "id"),
+					inner: value[open.end:tags[j].start],
+					start: open.start,
+					end:   tags[j].end,
+				})
+				i = j
+				break
+			}
+		}
+	}
+	return spans
+}
+
+// CheckXliff verifies that every <xliff:g> id of the source value is present exactly
+// once in the translated value, with the same inner placeholder. Values without a
+// source are not checked.
+func (res *Resources) CheckXliff(src *Resources) (issues []Issue) {
+
+	if src == nil {
+		return nil
+	}
+
+	for _, key := range res.sortedElementKeys() {
+		srcElement, ok := src.Elements[key]
+		if !ok {
+			continue
+		}
+
+		forEachText(res.Elements[key], srcElement, func(where, value, source string, hasSource bool) {
+			if !hasSource {
+				return
+			}
+			for _, problem := range compareXliff(value, source) {
+				issues = append(issues, Issue{
+					FilePath: res.FilePath,
+					Key:      key,
+					Message:  where + problem,
+				})
+			}
+		})
+	}
+	return issues
+}
+
+// compareXliff returns the differences between the <xliff:g> spans of value and source
+func compareXliff(value string, source string) (problems []string) {
+
+	spans := xliffSpans(value)
+	srcSpans := xliffSpans(source)
+
+	for _, srcSpan := range srcSpans {
+		var count int
+		var inner string
+		for _, span := range spans {
+			if span.id == srcSpan.id {
+				count++
+				inner = span.inner
+			}
+		}
+
+		switch {
+		case count == 0:
+			problems = append(problems, fmt.Sprintf("xliff:g id %q is missing", srcSpan.id))
+		case count > 1:
+			problems = append(problems, fmt.Sprintf("xliff:g id %q occurs %d times", srcSpan.id, count))
+		case strings.TrimSpace(inner) != strings.TrimSpace(srcSpan.inner):
+			problems = append(problems, fmt.Sprintf("xliff:g id %q has placeholder %q, source has %q", srcSpan.id, inner, srcSpan.inner))
+		}
+	}
+
+	for _, span := range spans {
+		var found bool
+		for _, srcSpan := range srcSpans {
+			if span.id == srcSpan.id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("xliff:g id %q is not in source", span.id))
+		}
+	}
+	return problems
+}